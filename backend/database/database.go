@@ -44,13 +44,288 @@ func InitDB() (*sql.DB, error) {
         progress INTEGER DEFAULT 0,
         hours_per_week DECIMAL(10,2) DEFAULT 0,
         load_per_month INTEGER DEFAULT 0,
+        status VARCHAR(50) NOT NULL DEFAULT 'backlog',
+        priority VARCHAR(20) NOT NULL DEFAULT 'normal',
+        start_date DATE,
+        due_date DATE,
+        parent_id INTEGER,
         user_id INTEGER NOT NULL,
+        created_by INTEGER,
+        recurring_id INTEGER,
+        occurrence_date DATE,
         created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
         updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        deleted_at DATETIME,
+        version INTEGER NOT NULL DEFAULT 1,
+        position REAL,
+        project_id INTEGER,
+        FOREIGN KEY (user_id) REFERENCES users (id),
+        FOREIGN KEY (created_by) REFERENCES users (id),
+        FOREIGN KEY (parent_id) REFERENCES tasks (id),
+        FOREIGN KEY (recurring_id) REFERENCES recurring_tasks (id),
+        FOREIGN KEY (project_id) REFERENCES projects (id)
+    );`
+
+	// Состояния и переходы workflow; department = '' — общий workflow по умолчанию
+	createWorkflowStatesTable := `
+    CREATE TABLE IF NOT EXISTS workflow_states (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        department VARCHAR(100) NOT NULL DEFAULT '',
+        key VARCHAR(50) NOT NULL,
+        name VARCHAR(100) NOT NULL,
+        position INTEGER NOT NULL DEFAULT 0,
+        is_initial BOOLEAN NOT NULL DEFAULT 0,
+        is_final BOOLEAN NOT NULL DEFAULT 0,
+        UNIQUE (department, key)
+    );`
+
+	createWorkflowTransitionsTable := `
+    CREATE TABLE IF NOT EXISTS workflow_transitions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        department VARCHAR(100) NOT NULL DEFAULT '',
+        from_state VARCHAR(50) NOT NULL,
+        to_state VARCHAR(50) NOT NULL,
+        UNIQUE (department, from_state, to_state)
+    );`
+
+	// Зависимости: task_id не может начаться, пока не завершена blocked_by_id
+	createTaskDependenciesTable := `
+    CREATE TABLE IF NOT EXISTS task_dependencies (
+        task_id INTEGER NOT NULL,
+        blocked_by_id INTEGER NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (task_id, blocked_by_id),
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (blocked_by_id) REFERENCES tasks (id)
+    );`
+
+	createTaskCommentsTable := `
+    CREATE TABLE IF NOT EXISTS task_comments (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        body TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );`
+
+	// Вложения: сами файлы лежат в ./data/attachments под именем sha256 содержимого
+	createTaskAttachmentsTable := `
+    CREATE TABLE IF NOT EXISTS task_attachments (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        filename VARCHAR(255) NOT NULL,
+        sha256 CHAR(64) NOT NULL,
+        size INTEGER NOT NULL,
+        mime_type VARCHAR(100) NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );`
+
+	// История изменений: changes — JSON вида {"поле": {"old": ..., "new": ...}}
+	createTaskHistoryTable := `
+    CREATE TABLE IF NOT EXISTS task_history (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id INTEGER NOT NULL,
+        user_id INTEGER,
+        action VARCHAR(20) NOT NULL,
+        changes TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+
+	// Метки задач; department = '' — общая метка для всех отделов
+	createLabelsTable := `
+    CREATE TABLE IF NOT EXISTS labels (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name VARCHAR(50) NOT NULL,
+        color VARCHAR(7) NOT NULL DEFAULT '',
+        department VARCHAR(100) NOT NULL DEFAULT '',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (department, name)
+    );`
+
+	createTaskLabelsTable := `
+    CREATE TABLE IF NOT EXISTS task_labels (
+        task_id INTEGER NOT NULL,
+        label_id INTEGER NOT NULL,
+        PRIMARY KEY (task_id, label_id),
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (label_id) REFERENCES labels (id)
+    );`
+
+	// Шаблоны повторяющихся задач: из них планировщик создаёт задачи по расписанию.
+	// last_run_date — дата последнего созданного повторения
+	createRecurringTasksTable := `
+    CREATE TABLE IF NOT EXISTS recurring_tasks (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        title VARCHAR(255) NOT NULL,
+        description TEXT,
+        hours_per_week DECIMAL(10,2) DEFAULT 0,
+        load_per_month INTEGER DEFAULT 0,
+        priority VARCHAR(20) NOT NULL DEFAULT 'normal',
+        frequency VARCHAR(10) NOT NULL,
+        interval INTEGER NOT NULL DEFAULT 1,
+        start_date DATE NOT NULL,
+        end_date DATE,
+        due_in_days INTEGER,
+        active BOOLEAN NOT NULL DEFAULT 1,
+        last_run_date DATE,
+        user_id INTEGER NOT NULL,
+        created_by INTEGER,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users (id),
+        FOREIGN KEY (created_by) REFERENCES users (id)
+    );`
+
+	// Учёт времени: запись таймера (started_at, ended_at IS NULL пока он идёт) или ручная запись за дату
+	createTimeEntriesTable := `
+    CREATE TABLE IF NOT EXISTS time_entries (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        entry_date DATE NOT NULL,
+        minutes INTEGER NOT NULL DEFAULT 0,
+        started_at DATETIME,
+        ended_at DATETIME,
+        note TEXT NOT NULL DEFAULT '',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );`
+
+	// Недельные табели: неделя — ISO-неделя вида 2026-W42, week_start — её понедельник
+	createTimesheetsTable := `
+    CREATE TABLE IF NOT EXISTS timesheets (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        week VARCHAR(8) NOT NULL,
+        week_start DATE NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'draft',
+        comment TEXT NOT NULL DEFAULT '',
+        submitted_at DATETIME,
+        reviewed_by INTEGER,
+        reviewed_at DATETIME,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (user_id, week),
+        FOREIGN KEY (user_id) REFERENCES users (id),
+        FOREIGN KEY (reviewed_by) REFERENCES users (id)
+    );`
+
+	// WIP-лимиты колонок доски: не больше wip_limit задач отдела в состоянии status
+	createBoardLimitsTable := `
+    CREATE TABLE IF NOT EXISTS board_limits (
+        department VARCHAR(100) NOT NULL DEFAULT '',
+        status VARCHAR(50) NOT NULL,
+        wip_limit INTEGER NOT NULL,
+        PRIMARY KEY (department, status)
+    );`
+
+	// Проекты объединяют задачи сотрудников разных отделов; владелец — участник по умолчанию
+	createProjectsTable := `
+    CREATE TABLE IF NOT EXISTS projects (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name VARCHAR(255) NOT NULL,
+        description TEXT NOT NULL DEFAULT '',
+        status VARCHAR(20) NOT NULL DEFAULT 'planned',
+        start_date DATE,
+        end_date DATE,
+        owner_id INTEGER NOT NULL,
+        created_by INTEGER,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (owner_id) REFERENCES users (id),
+        FOREIGN KEY (created_by) REFERENCES users (id)
+    );`
+
+	createProjectMembersTable := `
+    CREATE TABLE IF NOT EXISTS project_members (
+        project_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        PRIMARY KEY (project_id, user_id),
+        FOREIGN KEY (project_id) REFERENCES projects (id),
         FOREIGN KEY (user_id) REFERENCES users (id)
     );`
 
-	tables := []string{createUsersTable, createTasksTable}
+	// Наблюдатели задач; granted_by — кто подписал наблюдателя (при подписке на себя — он сам)
+	createTaskWatchersTable := `
+    CREATE TABLE IF NOT EXISTS task_watchers (
+        task_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        granted_by INTEGER NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (task_id, user_id),
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (user_id) REFERENCES users (id),
+        FOREIGN KEY (granted_by) REFERENCES users (id)
+    );`
+
+	// Шаблоны задач: значения по умолчанию, чек-лист (JSON-массив строк) и срок относительно даты запуска.
+	// department "" — общий шаблон
+	createTaskTemplatesTable := `
+    CREATE TABLE IF NOT EXISTS task_templates (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name VARCHAR(255) NOT NULL,
+        title VARCHAR(255) NOT NULL,
+        description TEXT NOT NULL DEFAULT '',
+        hours_per_week DECIMAL(10,2) DEFAULT 0,
+        load_per_month INTEGER DEFAULT 0,
+        priority VARCHAR(20) NOT NULL DEFAULT 'normal',
+        due_in_days INTEGER,
+        checklist TEXT NOT NULL DEFAULT '[]',
+        department VARCHAR(100) NOT NULL DEFAULT '',
+        created_by INTEGER,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (created_by) REFERENCES users (id)
+    );`
+
+	// Наборы шаблонов, которые создаются одним запросом (например, онбординг сотрудника)
+	createTemplateBundlesTable := `
+    CREATE TABLE IF NOT EXISTS template_bundles (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name VARCHAR(255) NOT NULL,
+        description TEXT NOT NULL DEFAULT '',
+        department VARCHAR(100) NOT NULL DEFAULT '',
+        created_by INTEGER,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (created_by) REFERENCES users (id)
+    );`
+
+	createBundleTemplatesTable := `
+    CREATE TABLE IF NOT EXISTS bundle_templates (
+        bundle_id INTEGER NOT NULL,
+        template_id INTEGER NOT NULL,
+        position INTEGER NOT NULL,
+        PRIMARY KEY (bundle_id, template_id),
+        FOREIGN KEY (bundle_id) REFERENCES template_bundles (id),
+        FOREIGN KEY (template_id) REFERENCES task_templates (id)
+    );`
+
+	// Пункты чек-листа задачи; доля отмеченных пунктов задаёт прогресс задачи без подзадач
+	createTaskChecklistItemsTable := `
+    CREATE TABLE IF NOT EXISTS task_checklist_items (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id INTEGER NOT NULL,
+        position INTEGER NOT NULL,
+        text VARCHAR(500) NOT NULL,
+        done BOOLEAN NOT NULL DEFAULT 0,
+        done_by INTEGER,
+        done_at DATETIME,
+        FOREIGN KEY (task_id) REFERENCES tasks (id),
+        FOREIGN KEY (done_by) REFERENCES users (id)
+    );`
+
+	tables := []string{
+		createUsersTable, createTasksTable, createWorkflowStatesTable, createWorkflowTransitionsTable,
+		createTaskDependenciesTable, createTaskCommentsTable, createTaskAttachmentsTable,
+		createTaskHistoryTable, createLabelsTable, createTaskLabelsTable, createRecurringTasksTable,
+		createTimeEntriesTable, createTimesheetsTable, createBoardLimitsTable, createProjectsTable,
+		createProjectMembersTable, createTaskWatchersTable, createTaskTemplatesTable, createTemplateBundlesTable,
+		createBundleTemplatesTable, createTaskChecklistItemsTable,
+	}
 	for _, table := range tables {
 		_, err = db.Exec(table)
 		if err != nil {
@@ -58,6 +333,66 @@ func InitDB() (*sql.DB, error) {
 		}
 	}
 
+	// Новые колонки для баз, созданных до их появления
+	columns := []struct{ table, column, definition string }{
+		{"tasks", "status", "VARCHAR(50) NOT NULL DEFAULT 'backlog'"},
+		{"tasks", "created_by", "INTEGER REFERENCES users (id)"},
+		{"tasks", "start_date", "DATE"},
+		{"tasks", "due_date", "DATE"},
+		{"tasks", "parent_id", "INTEGER REFERENCES tasks (id)"},
+		{"tasks", "deleted_at", "DATETIME"},
+		{"tasks", "priority", "VARCHAR(20) NOT NULL DEFAULT 'normal'"},
+		{"tasks", "recurring_id", "INTEGER REFERENCES recurring_tasks (id)"},
+		{"tasks", "occurrence_date", "DATE"},
+		{"tasks", "version", "INTEGER NOT NULL DEFAULT 1"},
+		{"tasks", "position", "REAL"},
+		{"tasks", "project_id", "INTEGER REFERENCES projects (id)"},
+	}
+	for _, col := range columns {
+		if err := ensureColumn(db, col.table, col.column, col.definition); err != nil {
+			return nil, err
+		}
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks (parent_id)",
+		"CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks (deleted_at)",
+		"CREATE INDEX IF NOT EXISTS idx_task_comments_task_id ON task_comments (task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_task_attachments_task_id ON task_attachments (task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_task_attachments_sha256 ON task_attachments (sha256)",
+		"CREATE INDEX IF NOT EXISTS idx_task_history_task_id ON task_history (task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_task_labels_label_id ON task_labels (label_id)",
+		// Одно повторение шаблона — не больше одной задачи, даже после перезапуска планировщика
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurring_occurrence ON tasks (recurring_id, occurrence_date) WHERE recurring_id IS NOT NULL",
+		"CREATE INDEX IF NOT EXISTS idx_tasks_project_id ON tasks (project_id)",
+		"CREATE INDEX IF NOT EXISTS idx_project_members_user_id ON project_members (user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_task_watchers_user_id ON task_watchers (user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_bundle_templates_template_id ON bundle_templates (template_id)",
+		"CREATE INDEX IF NOT EXISTS idx_task_checklist_items_task_id ON task_checklist_items (task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries (task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_time_entries_user_date ON time_entries (user_id, entry_date)",
+		// Не больше одного запущенного таймера на пользователя
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries (user_id) WHERE started_at IS NOT NULL AND ended_at IS NULL",
+	}
+	for _, index := range indexes {
+		if _, err := db.Exec(index); err != nil {
+			return nil, err
+		}
+	}
+
+	// Старые задачи считаем созданными их владельцами
+	if _, err := db.Exec("UPDATE tasks SET created_by = user_id WHERE created_by IS NULL"); err != nil {
+		return nil, err
+	}
+
+	if err := seedDefaultWorkflow(db); err != nil {
+		return nil, err
+	}
+
+	if err := initFullTextSearch(db); err != nil {
+		return nil, err
+	}
+
 	// Создание администратора по умолчанию
 	hashedPassword, _ := HashPassword("main12!@")
 	db.Exec(`INSERT OR IGNORE INTO users (username, password_hash, role, department) VALUES (?, ?, ?, ?)`, "admin", hashedPassword, "admin", "Администрация")
@@ -65,3 +400,31 @@ func InitDB() (*sql.DB, error) {
 	log.Println("Database initialized successfully")
 	return db, nil
 }
+
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	log.Printf("Adding column %s.%s", table, column)
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+)
+
+// FullTextSearch — доступен ли FTS5 (бинарник собран с тегом sqlite_fts5)
+var FullTextSearch bool
+
+// initFullTextSearch создаёт FTS5-индекс по названию и описанию задач и триггеры синхронизации.
+// Без FTS5 триггеры удаляются, иначе любая запись в tasks падала бы с "no such module".
+func initFullTextSearch(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return err
+	}
+
+	triggers := []string{"tasks_fts_ai", "tasks_fts_ad", "tasks_fts_au"}
+
+	if !enabled {
+		log.Println("FTS5 is not available, task search falls back to LIKE (build with -tags sqlite_fts5)")
+		for _, name := range triggers {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+				return err
+			}
+		}
+		FullTextSearch = false
+		return nil
+	}
+
+	// Если триггеров не было, индекс мог отстать от таблицы — перестраиваем его
+	var existing int
+	err := db.QueryRow(`
+        SELECT COUNT(*) FROM sqlite_master
+        WHERE type = 'trigger' AND name IN ('tasks_fts_ai', 'tasks_fts_ad', 'tasks_fts_au')
+    `).Scan(&existing)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5(
+            title, description,
+            content = 'tasks', content_rowid = 'id',
+            tokenize = 'unicode61 remove_diacritics 2'
+        );`,
+		`CREATE TRIGGER IF NOT EXISTS tasks_fts_ai AFTER INSERT ON tasks BEGIN
+            INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+        END;`,
+		`CREATE TRIGGER IF NOT EXISTS tasks_fts_ad AFTER DELETE ON tasks BEGIN
+            INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+        END;`,
+		`CREATE TRIGGER IF NOT EXISTS tasks_fts_au AFTER UPDATE OF title, description ON tasks BEGIN
+            INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+            INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+        END;`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if existing < len(triggers) {
+		log.Println("Rebuilding full-text index for tasks")
+		if _, err := db.Exec("INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild')"); err != nil {
+			return err
+		}
+	}
+
+	FullTextSearch = true
+	return nil
+}
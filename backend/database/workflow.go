@@ -0,0 +1,56 @@
+package database
+
+import "database/sql"
+
+// Workflow по умолчанию: backlog → in_progress → review → done
+func seedDefaultWorkflow(db *sql.DB) error {
+	states := []struct {
+		key, name          string
+		position           int
+		isInitial, isFinal bool
+	}{
+		{"backlog", "Бэклог", 1, true, false},
+		{"in_progress", "В работе", 2, false, false},
+		{"review", "На проверке", 3, false, false},
+		{"done", "Готово", 4, false, true},
+	}
+
+	// Засеваем только если общий workflow ещё не настроен
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM workflow_states WHERE department = ''").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, s := range states {
+		_, err := db.Exec(`
+            INSERT OR IGNORE INTO workflow_states (department, key, name, position, is_initial, is_final)
+            VALUES ('', ?, ?, ?, ?, ?)
+        `, s.key, s.name, s.position, s.isInitial, s.isFinal)
+		if err != nil {
+			return err
+		}
+	}
+
+	transitions := [][2]string{
+		{"backlog", "in_progress"},
+		{"in_progress", "backlog"},
+		{"in_progress", "review"},
+		{"review", "in_progress"},
+		{"review", "done"},
+		{"done", "in_progress"},
+	}
+	for _, t := range transitions {
+		_, err := db.Exec(`
+            INSERT OR IGNORE INTO workflow_transitions (department, from_state, to_state)
+            VALUES ('', ?, ?)
+        `, t[0], t[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	attachmentsDir    = "./data/attachments"
+	maxAttachmentSize = 20 << 20 // 20 МБ
+)
+
+// Разрешённые типы вложений (определяются по содержимому, а не по имени файла)
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+	"text/csv":        true,
+}
+
+// Офисные документы по содержимому выглядят как zip/ole, их тип берём по расширению
+var officeExtensions = map[string]bool{
+	".docx": true, ".xlsx": true, ".pptx": true,
+	".doc": true, ".xls": true, ".ppt": true,
+	".odt": true, ".ods": true,
+}
+
+type AttachmentHandler struct {
+	db *sql.DB
+}
+
+func NewAttachmentHandler(db *sql.DB) *AttachmentHandler {
+	return &AttachmentHandler{db: db}
+}
+
+// attachmentPath — путь к файлу по его sha256, с разбиением по первым двум символам
+func attachmentPath(hash string) string {
+	return filepath.Join(attachmentsDir, hash[:2], hash)
+}
+
+// detectAttachmentType определяет MIME-тип файла и проверяет его по списку разрешённых
+func detectAttachmentType(head []byte, filename string) (string, bool) {
+	detected := http.DetectContentType(head)
+	base, _, _ := mime.ParseMediaType(detected)
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if (base == "application/zip" || base == "application/octet-stream") && officeExtensions[ext] {
+		if byExt := mime.TypeByExtension(ext); byExt != "" {
+			return byExt, true
+		}
+		return base, true
+	}
+	if base == "text/plain" && ext == ".csv" {
+		return "text/csv", true
+	}
+
+	return detected, allowedAttachmentTypes[base]
+}
+
+// removeUnreferencedFiles удаляет с диска файлы, на которые больше не ссылается ни одно вложение
+func removeUnreferencedFiles(q querier, hashes []string) {
+	for _, hash := range hashes {
+		var count int
+		if err := q.QueryRow("SELECT COUNT(*) FROM task_attachments WHERE sha256 = ?", hash).Scan(&count); err != nil || count > 0 {
+			continue
+		}
+		os.Remove(attachmentPath(hash))
+	}
+}
+
+// attachmentHashes возвращает sha256 вложений задачи
+func attachmentHashes(q querier, taskID int) ([]string, error) {
+	rows, err := q.Query("SELECT DISTINCT sha256 FROM task_attachments WHERE task_id = ?", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+const attachmentSelectSQL = `
+    SELECT a.id, a.task_id, a.user_id, COALESCE(u.username, ''), a.filename, a.sha256, a.size, a.mime_type, a.created_at
+    FROM task_attachments a
+    LEFT JOIN users u ON a.user_id = u.id`
+
+func scanAttachment(row rowScanner) (models.TaskAttachment, error) {
+	var a models.TaskAttachment
+	err := row.Scan(&a.ID, &a.TaskID, &a.UserID, &a.Username, &a.Filename, &a.SHA256, &a.Size, &a.MimeType, &a.CreatedAt)
+	return a, err
+}
+
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(attachmentSelectSQL+" WHERE a.task_id = ? ORDER BY a.created_at, a.id", taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	attachments := []models.TaskAttachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		attachments = append(attachments, a)
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	taskID, ok := requireVisibleTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан или превышает допустимый размер"})
+		return
+	}
+	if fileHeader.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Размер файла не может превышать 20 МБ"})
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(src, head)
+	mimeType, allowed := detectAttachmentType(head[:n], fileHeader.Filename)
+	if !allowed {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Недопустимый тип файла: " + mimeType})
+		return
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Пишем во временный файл, попутно считая sha256, затем переносим под итоговое имя
+	if err := os.MkdirAll(attachmentsDir, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tmp, err := os.CreateTemp(attachmentsDir, "upload-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(src, maxAttachmentSize+1))
+	tmp.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Размер файла не может превышать 20 МБ"})
+		return
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	path := attachmentPath(hash)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	filename := filepath.Base(fileHeader.Filename)
+	result, err := h.db.Exec(`
+        INSERT INTO task_attachments (task_id, user_id, filename, sha256, size, mime_type)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, taskID, c.GetInt("userID"), filename, hash, size, mimeType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	attachment, err := scanAttachment(h.db.QueryRow(attachmentSelectSQL+" WHERE a.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// loadAttachment находит вложение задачи по :attachmentId
+func (h *AttachmentHandler) loadAttachment(c *gin.Context, taskID int) (models.TaskAttachment, bool) {
+	attachmentID, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return models.TaskAttachment{}, false
+	}
+
+	attachment, err := scanAttachment(h.db.QueryRow(attachmentSelectSQL+" WHERE a.id = ? AND a.task_id = ?", attachmentID, taskID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Вложение не найдено"})
+		return attachment, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return attachment, false
+	}
+	return attachment, true
+}
+
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+	attachment, ok := h.loadAttachment(c, taskID)
+	if !ok {
+		return
+	}
+
+	path := attachmentPath(attachment.SHA256)
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Файл вложения отсутствует на диске"})
+		return
+	}
+
+	c.Header("Content-Type", attachment.MimeType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.File(path)
+}
+
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	taskID, ok := requireVisibleTask(h.db, c)
+	if !ok {
+		return
+	}
+	attachment, ok := h.loadAttachment(c, taskID)
+	if !ok {
+		return
+	}
+
+	// Удалить вложение может загрузивший его или тот, кто может менять задачу
+	if attachment.UserID != c.GetInt("userID") {
+		canEdit, err := canEditTask(h.db, c, taskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !canEdit {
+			c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+			return
+		}
+	}
+
+	if _, err := h.db.Exec("DELETE FROM task_attachments WHERE id = ?", attachment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	removeUnreferencedFiles(h.db, []string{attachment.SHA256})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Вложение удалено"})
+}
@@ -1,15 +1,26 @@
 package handlers
 
 import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errHashMismatch — содержимое файла вложения из архива не совпадает с sha256 в его имени
+var errHashMismatch = errors.New("attachment hash mismatch")
+
+// Бэкап — zip-архив с tasks.db и каталогом attachments/ (файлы вложений).
+// Архив собирается во временном файле и отдаётся только целиком, чтобы ошибка не оставила клиенту обрезанный zip.
 func BackupDB(c *gin.Context) {
 	dbPath := "./data/tasks.db"
 
@@ -21,25 +32,56 @@ func BackupDB(c *gin.Context) {
 
 	// Создаем имя файла с timestamp
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	backupFileName := "backup_" + timestamp + ".db"
-
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Disposition", "attachment; filename="+backupFileName)
+	backupFileName := "backup_" + timestamp + ".zip"
 
-	// Открываем файл БД
-	file, err := os.Open(dbPath)
+	tmp, err := os.CreateTemp("", "backup_*.zip")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка открытия файла базы данных"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в создании бекапа"})
 		return
 	}
-	defer file.Close()
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-	// Копируем файл в response
-	_, err = io.Copy(c.Writer, file)
-	if err != nil {
+	archive := zip.NewWriter(tmp)
+
+	// Копируем файл БД в архив
+	if err := addFileToZip(archive, dbPath, "tasks.db"); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в отправке файла базы данных"})
 		return
 	}
+
+	// Файлы вложений; временные файлы незавершённых загрузок пропускаем
+	err = filepath.Walk(attachmentsDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !isAttachmentHash(info.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(attachmentsDir, file)
+		if err != nil {
+			return err
+		}
+		return addFileToZip(archive, file, "attachments/"+filepath.ToSlash(rel))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в отправке файлов вложений"})
+		return
+	}
+
+	if err := archive.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в создании бекапа"})
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в создании бекапа"})
+		return
+	}
+
+	c.FileAttachment(tmp.Name(), backupFileName)
 }
 
 func RestoreDB(c *gin.Context) {
@@ -52,12 +94,39 @@ func RestoreDB(c *gin.Context) {
 		return
 	}
 
-	// Проверяем расширение файла
-	if filepath.Ext(file.Filename) != ".db" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Только .db файлы допустимы"})
+	// Проверяем расширение файла: .zip — полный бэкап, .db — старый формат только с БД
+	ext := filepath.Ext(file.Filename)
+	if ext != ".db" && ext != ".zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Только .zip и .db файлы допустимы"})
 		return
 	}
 
+	var archive *zip.Reader
+	var dbEntry *zip.File
+	if ext == ".zip" {
+		src, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer src.Close()
+
+		archive, err = zip.NewReader(src, file.Size)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не является zip-архивом"})
+			return
+		}
+		for _, entry := range archive.File {
+			if entry.Name == "tasks.db" {
+				dbEntry = entry
+			}
+		}
+		if dbEntry == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "В архиве нет tasks.db"})
+			return
+		}
+	}
+
 	// Создаем резервную копию текущей БД
 	if _, err := os.Stat(dbPath); err == nil {
 		backupName := "backup_before_restore_" + time.Now().Format("2006-01-02_15-04-05") + ".db"
@@ -68,13 +137,114 @@ func RestoreDB(c *gin.Context) {
 		}
 	}
 
-	// Сохраняем новую БД
-	if err := c.SaveUploadedFile(file, dbPath); err != nil {
+	if archive == nil {
+		// Сохраняем новую БД
+		if err := c.SaveUploadedFile(file, dbPath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в сохранении новой базы данных"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Система успешно восстановлена из бекапа"})
+		return
+	}
+
+	if err := extractZipFile(dbEntry, dbPath, ""); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в сохранении новой базы данных"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Система успешно восстановлена из бекапа"})
+	// Вложения адресуются по содержимому, поэтому существующие файлы не перезаписываем,
+	// а файлы, чьё содержимое не совпадает с именем, пропускаем
+	restored, skipped := 0, 0
+	for _, entry := range archive.File {
+		rel, ok := strings.CutPrefix(entry.Name, "attachments/")
+		if !ok || entry.FileInfo().IsDir() {
+			continue
+		}
+		hash := path.Base(rel)
+		if !isAttachmentHash(hash) || rel != hash[:2]+"/"+hash {
+			continue
+		}
+		target := attachmentPath(hash)
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в восстановлении вложений"})
+			return
+		}
+		err := extractZipFile(entry, target, hash)
+		if errors.Is(err, errHashMismatch) {
+			skipped++
+			continue
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка в восстановлении вложений"})
+			return
+		}
+		restored++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Система успешно восстановлена из бекапа",
+		"attachments_restored": restored,
+		"attachments_skipped":  skipped,
+	})
+}
+
+// isAttachmentHash — имя файла вложения: sha256 в hex
+func isAttachmentHash(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+func addFileToZip(archive *zip.Writer, src, name string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(destination, source)
+	return err
+}
+
+// extractZipFile распаковывает запись архива через временный файл, чтобы не оставить обрезанный файл.
+// Если задан sum, файл сохраняется, только когда sha256 содержимого совпадает с ним, иначе — errHashMismatch.
+func extractZipFile(entry *zip.File, dst, sum string) error {
+	source, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	tmp := dst + ".restore"
+	destination, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(destination, hasher), source); err != nil {
+		destination.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := destination.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if sum != "" && hex.EncodeToString(hasher.Sum(nil)) != sum {
+		os.Remove(tmp)
+		return errHashMismatch
+	}
+	return os.Rename(tmp, dst)
 }
 
 func copyFile(src, dst string) error {
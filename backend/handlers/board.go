@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Порядок задачи t в колонке доски. Пока задачу не двигали, её место определяется ID,
+// поэтому новые задачи оказываются внизу колонки без пересчёта остальных.
+const boardPositionSQL = `COALESCE(t.position, t.id)`
+
+// Задачи колонки доски: состояние status у задач сотрудников отдела department (запрос с алиасами t и u)
+const boardColumnSQL = `t.deleted_at IS NULL AND t.status = ? AND COALESCE(u.department, '') = ?`
+
+// Если соседние позиции сблизились сильнее, колонка перенумеровывается
+const minPositionGap = 1e-9
+
+type BoardHandler struct {
+	db *sql.DB
+}
+
+func NewBoardHandler(db *sql.DB) *BoardHandler {
+	return &BoardHandler{db: db}
+}
+
+// boardDepartment — доска своего отдела; админ может выбрать отдел через ?department=
+func boardDepartment(c *gin.Context) string {
+	department := c.GetString("userDepartment")
+	if c.GetString("userRole") == "admin" {
+		if d, ok := c.GetQuery("department"); ok {
+			department = d
+		}
+	}
+	return department
+}
+
+func wipLimits(q querier, department string) (map[string]int, error) {
+	rows, err := q.Query("SELECT status, wip_limit FROM board_limits WHERE department = ?", department)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := map[string]int{}
+	for rows.Next() {
+		var status string
+		var limit int
+		if err := rows.Scan(&status, &limit); err != nil {
+			return nil, err
+		}
+		limits[status] = limit
+	}
+	return limits, rows.Err()
+}
+
+// GetBoard группирует задачи отдела по состояниям его workflow.
+// Пользователь видит на доске только свои задачи, как и в GetTasks.
+func (h *BoardHandler) GetBoard(c *gin.Context) {
+	department := boardDepartment(c)
+
+	wf, err := loadWorkflow(h.db, department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	limits, err := wipLimits(h.db, department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	board := models.Board{Department: department, Columns: []models.BoardColumn{}}
+	index := map[string]int{}
+	addColumn := func(key, name string, isFinal bool) int {
+		column := models.BoardColumn{Key: key, Name: name, IsFinal: isFinal, Tasks: []models.Task{}}
+		if limit, ok := limits[key]; ok {
+			column.WIPLimit = &limit
+		}
+		index[key] = len(board.Columns)
+		board.Columns = append(board.Columns, column)
+		return index[key]
+	}
+	for _, s := range wf.States {
+		addColumn(s.Key, s.Name, s.IsFinal)
+	}
+
+	scope, args := taskScope(c)
+	rows, err := h.db.Query(taskSelectSQL+`
+        WHERE COALESCE(u.department, '') = ? AND `+scope+`
+        ORDER BY `+boardPositionSQL+`, t.id
+    `, append([]interface{}{department}, args...)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Задачи в состоянии, которого больше нет в workflow, попадают в отдельную колонку
+		i, ok := index[task.Status]
+		if !ok {
+			i = addColumn(task.Status, task.StatusName, false)
+		}
+		board.Columns[i].Tasks = append(board.Columns[i].Tasks, task)
+		board.Columns[i].Count++
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// UpdateLimits задаёт WIP-лимиты колонок: {"limits": {"in_progress": 5}}; лимит 0 снимает ограничение.
+// Менеджер настраивает свой отдел, админ — любой через ?department=.
+func (h *BoardHandler) UpdateLimits(c *gin.Context) {
+	var request struct {
+		Limits map[string]int `json:"limits" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	department := boardDepartment(c)
+
+	wf, err := loadWorkflow(h.db, department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for status, limit := range request.Limits {
+		if !workflowHasState(wf, status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный статус: " + status})
+			return
+		}
+		if limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "WIP-лимит не может быть отрицательным"})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	for status, limit := range request.Limits {
+		if limit == 0 {
+			_, err = tx.Exec("DELETE FROM board_limits WHERE department = ? AND status = ?", department, status)
+		} else {
+			_, err = tx.Exec(`
+                INSERT INTO board_limits (department, status, wip_limit) VALUES (?, ?, ?)
+                ON CONFLICT (department, status) DO UPDATE SET wip_limit = excluded.wip_limit
+            `, department, status, limit)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	limits, err := wipLimits(tx, department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"department": department, "limits": limits})
+}
+
+// columnPosition возвращает позицию задачи anchorID в колонке; ok = false, если задачи в колонке нет
+func columnPosition(q querier, anchorID int, status, department string) (float64, bool, error) {
+	var position float64
+	err := q.QueryRow(`
+        SELECT `+boardPositionSQL+` FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE t.id = ? AND `+boardColumnSQL, anchorID, status, department).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return position, err == nil, err
+}
+
+// neighbourPosition — ближайшая позиция в колонке ниже (after = true) или выше anchor, не считая taskID
+func neighbourPosition(q querier, taskID int, status, department string, anchor float64, after bool) (sql.NullFloat64, error) {
+	query := `SELECT MIN(` + boardPositionSQL + `) FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE ` + boardColumnSQL + ` AND t.id != ? AND ` + boardPositionSQL + ` > ?`
+	if !after {
+		query = `SELECT MAX(` + boardPositionSQL + `) FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE ` + boardColumnSQL + ` AND t.id != ? AND ` + boardPositionSQL + ` < ?`
+	}
+	var position sql.NullFloat64
+	err := q.QueryRow(query, status, department, taskID, anchor).Scan(&position)
+	return position, err
+}
+
+// renumberColumn раздаёт задачам колонки позиции 1, 2, 3... в текущем порядке
+func renumberColumn(q querier, status, department string) error {
+	rows, err := q.Query(`
+        SELECT t.id FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE `+boardColumnSQL+`
+        ORDER BY `+boardPositionSQL+`, t.id
+    `, status, department)
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for i, id := range ids {
+		if _, err := q.Exec("UPDATE tasks SET position = ? WHERE id = ?", i+1, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// movePosition вычисляет позицию задачи в колонке: сразу после afterID, сразу перед beforeID
+// или в конце колонки. Меняется только позиция перемещаемой задачи — между соседями берётся середина.
+func movePosition(q querier, taskID int, status, department string, afterID, beforeID int) (float64, int, gin.H) {
+	for attempt := 0; attempt < 2; attempt++ {
+		var position float64
+		var low, high sql.NullFloat64
+
+		switch {
+		case afterID != 0 || beforeID != 0:
+			anchorID, after := afterID, true
+			if afterID == 0 {
+				anchorID, after = beforeID, false
+			}
+			if anchorID == taskID {
+				return 0, http.StatusBadRequest, gin.H{"error": "Нельзя поставить задачу рядом с самой собой"}
+			}
+			anchor, ok, err := columnPosition(q, anchorID, status, department)
+			if err != nil {
+				return 0, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+			if !ok {
+				return 0, http.StatusBadRequest, gin.H{"error": "Соседняя задача не найдена в колонке"}
+			}
+			neighbour, err := neighbourPosition(q, taskID, status, department, anchor, after)
+			if err != nil {
+				return 0, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+			if after {
+				low, high = sql.NullFloat64{Float64: anchor, Valid: true}, neighbour
+			} else {
+				low, high = neighbour, sql.NullFloat64{Float64: anchor, Valid: true}
+			}
+		default:
+			err := q.QueryRow(`
+                SELECT MAX(`+boardPositionSQL+`) FROM tasks t JOIN users u ON t.user_id = u.id
+                WHERE `+boardColumnSQL+` AND t.id != ?
+            `, status, department, taskID).Scan(&low)
+			if err != nil {
+				return 0, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+		}
+
+		switch {
+		case low.Valid && high.Valid:
+			if high.Float64-low.Float64 < minPositionGap {
+				if err := renumberColumn(q, status, department); err != nil {
+					return 0, http.StatusInternalServerError, gin.H{"error": err.Error()}
+				}
+				continue
+			}
+			position = (low.Float64 + high.Float64) / 2
+		case low.Valid:
+			position = low.Float64 + 1
+		case high.Valid:
+			position = high.Float64 - 1
+		default:
+			position = 1
+		}
+		return position, 0, nil
+	}
+	return 0, http.StatusInternalServerError, gin.H{"error": "Не удалось вычислить позицию задачи"}
+}
+
+// MoveTask переносит задачу в колонку status (по умолчанию — текущую) и ставит её после after_id
+// или перед before_id, а без них — в конец колонки. Смена колонки проходит по правилам UpdateTask
+// и учитывает WIP-лимит колонки.
+func (h *BoardHandler) MoveTask(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Status   string `json:"status"`
+		AfterID  int    `json:"after_id"`
+		BeforeID int    `json:"before_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.AfterID != 0 && request.BeforeID != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите только after_id или только before_id"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if status, body := checkIfMatch(tx, c, taskID); status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	task, err := loadTask(tx, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Status == "" {
+		request.Status = task.Status
+	}
+
+	var overloads []models.UserCapacity
+	if request.Status != task.Status {
+		limits, err := wipLimits(tx, task.Department)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if limit, ok := limits[request.Status]; ok {
+			var count int
+			err := tx.QueryRow(`
+                SELECT COUNT(*) FROM tasks t JOIN users u ON t.user_id = u.id WHERE `+boardColumnSQL,
+				request.Status, task.Department).Scan(&count)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if count >= limit {
+				c.JSON(http.StatusConflict, gin.H{"error": "В колонке достигнут WIP-лимит", "wip_limit": limit, "count": count})
+				return
+			}
+		}
+
+		task.Status = request.Status
+		var status int
+		var body gin.H
+		overloads, status, body = updateTask(tx, c, taskID, task)
+		if status != 0 {
+			c.JSON(status, body)
+			return
+		}
+	}
+
+	position, status, body := movePosition(tx, taskID, request.Status, task.Department, request.AfterID, request.BeforeID)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+	// Перестановка внутри колонки — тоже изменение задачи: новая версия, чтобы сменился ETag
+	_, err = tx.Exec("UPDATE tasks SET position = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", position, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	moved, err := loadTask(tx, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	moved.CapacityWarnings = overloads
+	c.Header("ETag", taskETag(moved.Version))
+	c.JSON(http.StatusOK, moved)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkTasks = 100
+
+// taskPatch — частичное изменение задачи: применяются только переданные поля
+type taskPatch struct {
+	Title        *string  `json:"title"`
+	Description  *string  `json:"description"`
+	Progress     *int     `json:"progress"`
+	HoursPerWeek *float64 `json:"hours_per_week"`
+	LoadPerMonth *int     `json:"load_per_month"`
+	Status       *string  `json:"status"`
+	Priority     *string  `json:"priority"`
+	StartDate    *string  `json:"start_date"`
+	DueDate      *string  `json:"due_date"`
+}
+
+func (p taskPatch) apply(task *models.Task) {
+	if p.Title != nil {
+		task.Title = *p.Title
+	}
+	if p.Description != nil {
+		task.Description = *p.Description
+	}
+	if p.Progress != nil {
+		task.Progress = *p.Progress
+	}
+	if p.HoursPerWeek != nil {
+		task.HoursPerWeek = *p.HoursPerWeek
+	}
+	if p.LoadPerMonth != nil {
+		task.LoadPerMonth = *p.LoadPerMonth
+	}
+	if p.Status != nil {
+		task.Status = *p.Status
+	}
+	if p.Priority != nil {
+		task.Priority = *p.Priority
+	}
+	if p.StartDate != nil {
+		task.StartDate = *p.StartDate
+	}
+	if p.DueDate != nil {
+		task.DueDate = *p.DueDate
+	}
+}
+
+func (p taskPatch) empty() bool {
+	return p == taskPatch{}
+}
+
+// loadTask читает задачу не из корзины
+func loadTask(q querier, taskID int) (models.Task, error) {
+	return scanTask(q.QueryRow(taskSelectSQL+" WHERE t.id = ? AND t.deleted_at IS NULL", taskID))
+}
+
+type bulkRequest struct {
+	IDs        []int     `json:"ids" binding:"required"`
+	Operation  string    `json:"operation" binding:"required"` // update, reassign или delete
+	Fields     taskPatch `json:"fields"`                       // для update
+	AssigneeID int       `json:"assignee_id"`                  // для reassign
+	Children   string    `json:"children"`                     // для delete: reparent или cascade
+}
+
+// BulkTasks применяет одну операцию к списку задач в одной транзакции.
+// Права проверяются для каждой задачи так же, как в UpdateTask/DeleteTask/ReassignTask;
+// если хоть одна задача не прошла, изменения откатываются целиком.
+func (h *TaskHandler) BulkTasks(c *gin.Context) {
+	var request bulkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(request.IDs) == 0 || len(request.IDs) > maxBulkTasks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Количество задач должно быть от 1 до " + strconv.Itoa(maxBulkTasks)})
+		return
+	}
+
+	switch request.Operation {
+	case "update":
+		if request.Fields.empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите изменяемые поля"})
+			return
+		}
+	case "reassign":
+		// Как и PUT /tasks/:id/assignee — только для менеджеров и админов
+		if c.GetString("userRole") == "user" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Manager or admin access required"})
+			return
+		}
+		if request.AssigneeID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите assignee_id"})
+			return
+		}
+	case "delete":
+		if request.Children == "" {
+			request.Children = "reparent"
+		}
+		if request.Children != "reparent" && request.Children != "cascade" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "children может быть reparent или cascade"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operation может быть update, reassign или delete"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	results := []gin.H{}
+	failed := 0
+	seen := map[int]bool{}
+	deletedInBatch := map[int]bool{}
+	for _, taskID := range request.IDs {
+		if seen[taskID] {
+			continue
+		}
+		seen[taskID] = true
+
+		var status int
+		var body gin.H
+		result := gin.H{}
+
+		switch request.Operation {
+		case "update":
+			// Отсутствующую задачу и задачу без доступа различает updateTask
+			task, err := loadTask(tx, taskID)
+			if err != nil && err != sql.ErrNoRows {
+				status, body = http.StatusInternalServerError, gin.H{"error": err.Error()}
+				break
+			}
+			request.Fields.apply(&task)
+			var overloads []models.UserCapacity
+			overloads, status, body = updateTask(tx, c, taskID, task)
+			if len(overloads) > 0 {
+				result["capacity_warnings"] = overloads
+			}
+		case "reassign":
+			var newStatus string
+			var overloads []models.UserCapacity
+			newStatus, overloads, status, body = reassignTask(tx, c, taskID, request.AssigneeID)
+			result["task_status"] = newStatus
+			if len(overloads) > 0 {
+				result["capacity_warnings"] = overloads
+			}
+		case "delete":
+			if deletedInBatch[taskID] {
+				// Уже отправлена в корзину вместе с родительской задачей из этого же списка
+				result["deleted"] = []int{}
+				break
+			}
+			var deleted []int
+			deleted, status, body = deleteTask(tx, c, taskID, request.Children)
+			for _, id := range deleted {
+				deletedInBatch[id] = true
+			}
+			result["deleted"] = deleted
+		}
+
+		if status != 0 {
+			failed++
+			result = body
+		} else {
+			status = http.StatusOK
+		}
+		result["id"] = taskID
+		result["status"] = status
+		results = append(results, result)
+	}
+
+	if failed > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Операция отменена, задач с ошибками: " + strconv.Itoa(failed) + " из " + strconv.Itoa(len(results)),
+			"applied": false,
+			"results": results,
+		})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Операция выполнена", "applied": true, "results": results})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Загрузка сотрудника в месяц, которую нельзя превышать (в процентах, как load_per_month)
+const maxMonthLoad = 100
+
+// Дальше этого горизонта загрузка при изменении задачи не проверяется
+const capacityMonthsAhead = 12
+
+const monthLayout = "2006-01"
+
+// CapacityStrict — при перегрузке сотрудника создание и изменение задачи отклоняются с 409
+// вместо предупреждения в ответе. Задаётся переменной окружения CAPACITY_STRICT.
+var CapacityStrict bool
+
+// Задача t загружает владельца в месяце, если она не в корзине, не завершена и её период
+// (от start_date или даты создания до due_date) пересекается с месяцем.
+// Параметры — последний и первый день месяца; запрос должен содержать алиасы t и u.
+const capacityTaskSQL = `t.deleted_at IS NULL AND NOT ` + statusFinalSQL + `
+    AND COALESCE(t.start_date, DATE(t.created_at)) <= ? AND (t.due_date IS NULL OR t.due_date >= ?)`
+
+const userCapacitySQL = `
+    SELECT u.id, u.username, COALESCE(u.department, ''), COALESCE(SUM(t.load_per_month), 0), COUNT(t.id)
+    FROM users u
+    LEFT JOIN tasks t ON t.user_id = u.id AND ` + capacityTaskSQL
+
+type CapacityHandler struct {
+	db *sql.DB
+}
+
+func NewCapacityHandler(db *sql.DB) *CapacityHandler {
+	return &CapacityHandler{db: db}
+}
+
+// monthBounds возвращает первый и последний день месяца в формате ГГГГ-ММ-ДД
+func monthBounds(month time.Time) (string, string) {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return first.Format(dateLayout), first.AddDate(0, 1, -1).Format(dateLayout)
+}
+
+func scanUserCapacity(row rowScanner, month time.Time) (models.UserCapacity, error) {
+	var uc models.UserCapacity
+	err := row.Scan(&uc.UserID, &uc.Username, &uc.Department, &uc.Load, &uc.TaskCount)
+	uc.Month = month.Format(monthLayout)
+	uc.Capacity = maxMonthLoad
+	uc.Overloaded = uc.Load > maxMonthLoad
+	return uc, err
+}
+
+func userMonthLoad(q querier, userID int, month time.Time) (models.UserCapacity, error) {
+	first, last := monthBounds(month)
+	return scanUserCapacity(q.QueryRow(userCapacitySQL+" WHERE u.id = ? GROUP BY u.id", last, first, userID), month)
+}
+
+// taskMonths — месяцы, на загрузку которых влияет задача: от её начала до срока,
+// но не раньше текущего месяца и не дальше capacityMonthsAhead. Задача без срока проверяется по первому месяцу.
+func taskMonths(task models.Task, today time.Time) []time.Time {
+	current := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	from := current
+	if start, err := time.Parse(dateLayout, task.StartDate); err == nil && start.After(current) {
+		from = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	to := from
+	if due, err := time.Parse(dateLayout, task.DueDate); err == nil {
+		to = time.Date(due.Year(), due.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var months []time.Time
+	for month := from; !month.After(to) && len(months) < capacityMonthsAhead; month = month.AddDate(0, 1, 0) {
+		months = append(months, month)
+	}
+	return months
+}
+
+// capacityGuard запоминает загрузку сотрудника до изменения задачи, чтобы после него
+// найти месяцы, где изменение довело загрузку выше maxMonthLoad
+type capacityGuard struct {
+	userID int
+	months []time.Time
+	before map[time.Time]int
+}
+
+// newCapacityGuard учитывает месяцы всех переданных задач — например, всех задач набора шаблонов
+func newCapacityGuard(q querier, userID int, tasks ...models.Task) (*capacityGuard, error) {
+	g := &capacityGuard{userID: userID, before: map[time.Time]int{}}
+	seen := map[time.Time]bool{}
+	for _, task := range tasks {
+		for _, month := range taskMonths(task, localDate(time.Now())) {
+			if !seen[month] {
+				seen[month] = true
+				g.months = append(g.months, month)
+			}
+		}
+	}
+	for _, month := range g.months {
+		uc, err := userMonthLoad(q, userID, month)
+		if err != nil {
+			return nil, err
+		}
+		g.before[month] = uc.Load
+	}
+	return g, nil
+}
+
+// check возвращает месяцы, в которых загрузка выросла и превысила maxMonthLoad
+func (g *capacityGuard) check(q querier) ([]models.UserCapacity, error) {
+	var overloads []models.UserCapacity
+	for _, month := range g.months {
+		uc, err := userMonthLoad(q, g.userID, month)
+		if err != nil {
+			return nil, err
+		}
+		if uc.Overloaded && uc.Load > g.before[month] {
+			overloads = append(overloads, uc)
+		}
+	}
+	return overloads, nil
+}
+
+// capacityConflict в строгом режиме превращает перегрузку в 409, иначе возвращает 0
+func capacityConflict(overloads []models.UserCapacity) (int, gin.H) {
+	if CapacityStrict && len(overloads) > 0 {
+		return http.StatusConflict, gin.H{"error": "Изменение перегружает сотрудника", "capacity_warnings": overloads}
+	}
+	return 0, nil
+}
+
+// GetCapacity возвращает загрузку сотрудников и отделов за месяц ?month=ГГГГ-ММ (по умолчанию — текущий).
+// Менеджер видит свой отдел, админ — все отделы или ?department=.
+func (h *CapacityHandler) GetCapacity(c *gin.Context) {
+	month := localDate(time.Now())
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.Parse(monthLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Месяц должен быть в формате ГГГГ-ММ"})
+			return
+		}
+		month = parsed
+	}
+	first, last := monthBounds(month)
+
+	query := userCapacitySQL
+	args := []interface{}{last, first}
+	if c.GetString("userRole") != "admin" {
+		query += " WHERE u.department = ?"
+		args = append(args, c.GetString("userDepartment"))
+	} else if department := c.Query("department"); department != "" {
+		query += " WHERE u.department = ?"
+		args = append(args, department)
+	}
+	query += " GROUP BY u.id ORDER BY u.department, u.username"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	users := []models.UserCapacity{}
+	departments := []models.DepartmentCapacity{}
+	index := map[string]int{}
+	for rows.Next() {
+		uc, err := scanUserCapacity(rows, month)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		users = append(users, uc)
+
+		i, ok := index[uc.Department]
+		if !ok {
+			i = len(departments)
+			index[uc.Department] = i
+			departments = append(departments, models.DepartmentCapacity{Department: uc.Department})
+		}
+		d := &departments[i]
+		d.Users++
+		d.Load += uc.Load
+		d.Capacity += maxMonthLoad
+		if uc.Overloaded {
+			d.OverloadedUsers++
+		}
+		d.Overloaded = d.Load > d.Capacity
+	}
+
+	c.JSON(http.StatusOK, gin.H{"month": month.Format(monthLayout), "users": users, "departments": departments})
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Не больше стольких пунктов в чек-листе задачи или шаблона
+const maxChecklistItems = 100
+
+type ChecklistHandler struct {
+	db *sql.DB
+}
+
+func NewChecklistHandler(db *sql.DB) *ChecklistHandler {
+	return &ChecklistHandler{db: db}
+}
+
+// checklistProgress возвращает долю отмеченных пунктов чек-листа задачи (в процентах) и число пунктов
+func checklistProgress(q querier, taskID int) (int, int, error) {
+	var total, done int
+	err := q.QueryRow("SELECT COUNT(*), COALESCE(SUM(done), 0) FROM task_checklist_items WHERE task_id = ?", taskID).Scan(&total, &done)
+	if err != nil || total == 0 {
+		return 0, 0, err
+	}
+	return int(math.Round(float64(done) * 100 / float64(total))), total, nil
+}
+
+// validateChecklist нормализует пункты чек-листа и возвращает текст ошибки, либо пустую строку
+func validateChecklist(items []string) string {
+	if len(items) > maxChecklistItems {
+		return "В чек-листе не может быть больше " + strconv.Itoa(maxChecklistItems) + " пунктов"
+	}
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+		if items[i] == "" {
+			return "Пункт чек-листа не может быть пустым"
+		}
+		if len([]rune(items[i])) > 500 {
+			return "Пункт чек-листа не может быть длиннее 500 символов"
+		}
+	}
+	return ""
+}
+
+// addChecklistItems дописывает пункты в конец чек-листа задачи
+func addChecklistItems(q querier, taskID int, items []string) error {
+	for _, text := range items {
+		_, err := q.Exec(`
+            INSERT INTO task_checklist_items (task_id, position, text)
+            SELECT ?, COALESCE(MAX(position), 0) + 1, ? FROM task_checklist_items WHERE task_id = ?
+        `, taskID, text, taskID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func taskChecklist(q querier, taskID int) ([]models.ChecklistItem, error) {
+	rows, err := q.Query(`
+        SELECT id, task_id, text, done, done_by, done_at FROM task_checklist_items
+        WHERE task_id = ?
+        ORDER BY position, id
+    `, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ChecklistItem{}
+	for rows.Next() {
+		var item models.ChecklistItem
+		var doneBy sql.NullInt64
+		var doneAt sql.NullTime
+		if err := rows.Scan(&item.ID, &item.TaskID, &item.Text, &item.Done, &doneBy, &doneAt); err != nil {
+			return nil, err
+		}
+		if doneBy.Valid {
+			id := int(doneBy.Int64)
+			item.DoneBy = &id
+		}
+		if doneAt.Valid {
+			item.DoneAt = &doneAt.Time
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// respondChecklist отдаёт чек-лист задачи вместе с её текущим прогрессом
+func (h *ChecklistHandler) respondChecklist(c *gin.Context, status, taskID int) {
+	items, err := taskChecklist(h.db, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var progress int
+	if err := h.db.QueryRow("SELECT progress FROM tasks WHERE id = ?", taskID).Scan(&progress); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, gin.H{"task_id": taskID, "progress": progress, "items": items})
+}
+
+func (h *ChecklistHandler) GetChecklist(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+	h.respondChecklist(c, http.StatusOK, taskID)
+}
+
+// AddChecklistItem добавляет пункт в конец чек-листа и пересчитывает прогресс задачи
+func (h *ChecklistHandler) AddChecklistItem(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items := []string{request.Text}
+	if msg := validateChecklist(items); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM task_checklist_items WHERE task_id = ?", taskID).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count >= maxChecklistItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В чек-листе не может быть больше " + strconv.Itoa(maxChecklistItems) + " пунктов"})
+		return
+	}
+
+	if err := addChecklistItems(tx, taskID, items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rollupProgress(tx, taskID, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondChecklist(c, http.StatusCreated, taskID)
+}
+
+// loadChecklistItem проверяет, что пункт :itemId принадлежит задаче, и возвращает его отметку
+func loadChecklistItem(q querier, c *gin.Context, taskID int) (int, bool, bool) {
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist item ID"})
+		return 0, false, false
+	}
+
+	var done bool
+	err = q.QueryRow("SELECT done FROM task_checklist_items WHERE id = ? AND task_id = ?", itemID, taskID).Scan(&done)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пункт чек-листа не найден"})
+		return 0, false, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false, false
+	}
+	return itemID, done, true
+}
+
+// UpdateChecklistItem меняет текст пункта и/или отмечает его ({"done": true}).
+// Как и прогресс в UpdateTask, отметка не ставится, пока задачу блокируют незавершённые задачи.
+func (h *ChecklistHandler) UpdateChecklistItem(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Text *string `json:"text"`
+		Done *bool   `json:"done"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Text == nil && request.Done == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите изменяемые поля"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	itemID, done, ok := loadChecklistItem(tx, c, taskID)
+	if !ok {
+		return
+	}
+
+	if request.Text != nil {
+		items := []string{*request.Text}
+		if msg := validateChecklist(items); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if _, err := tx.Exec("UPDATE task_checklist_items SET text = ? WHERE id = ?", items[0], itemID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if request.Done != nil && *request.Done != done {
+		if *request.Done && c.Query("override_blockers") != "true" {
+			blockers, err := openBlockers(tx, taskID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if len(blockers) > 0 {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":      "Задача заблокирована незавершёнными задачами",
+					"blocked_by": blockers,
+				})
+				return
+			}
+		}
+
+		var doneBy interface{}
+		if *request.Done {
+			doneBy = c.GetInt("userID")
+		}
+		_, err := tx.Exec(`
+            UPDATE task_checklist_items
+            SET done = ?, done_by = ?, done_at = CASE WHEN ? THEN CURRENT_TIMESTAMP END
+            WHERE id = ?
+        `, *request.Done, doneBy, *request.Done, itemID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := rollupProgress(tx, taskID, c.GetInt("userID")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondChecklist(c, http.StatusOK, taskID)
+}
+
+// DeleteChecklistItem удаляет пункт; когда удалён последний, задача сохраняет набранный прогресс
+func (h *ChecklistHandler) DeleteChecklistItem(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	itemID, _, ok := loadChecklistItem(tx, c, taskID)
+	if !ok {
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM task_checklist_items WHERE id = ?", itemID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rollupProgress(tx, taskID, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondChecklist(c, http.StatusOK, taskID)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	db *sql.DB
+}
+
+func NewCommentHandler(db *sql.DB) *CommentHandler {
+	return &CommentHandler{db: db}
+}
+
+const commentSelectSQL = `
+    SELECT c.id, c.task_id, c.user_id, COALESCE(u.username, ''), c.body,
+           c.updated_at > c.created_at, c.created_at, c.updated_at
+    FROM task_comments c
+    LEFT JOIN users u ON c.user_id = u.id`
+
+func scanComment(row rowScanner) (models.TaskComment, error) {
+	var comment models.TaskComment
+	err := row.Scan(&comment.ID, &comment.TaskID, &comment.UserID, &comment.Username, &comment.Body,
+		&comment.Edited, &comment.CreatedAt, &comment.UpdatedAt)
+	return comment, err
+}
+
+// requireVisibleTask отвечает 404, если задачи нет или она не видна пользователю (taskScope)
+func requireVisibleTask(q querier, c *gin.Context) (int, bool) {
+	return requireTaskIn(q, c, taskVisible)
+}
+
+// requireReadableTask — как requireVisibleTask, но пропускает и наблюдателей; только для чтения
+func requireReadableTask(q querier, c *gin.Context) (int, bool) {
+	return requireTaskIn(q, c, taskReadable)
+}
+
+func requireTaskIn(q querier, c *gin.Context, check func(querier, *gin.Context, int) (bool, error)) (int, bool) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return 0, false
+	}
+
+	visible, err := check(q, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Задача не найдена"})
+		return 0, false
+	}
+	return taskID, true
+}
+
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(commentSelectSQL+`
+        WHERE c.task_id = ?
+        ORDER BY c.created_at, c.id
+    `, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	comments := []models.TaskComment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		comments = append(comments, comment)
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	taskID, ok := requireVisibleTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(request.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Комментарий не может быть пустым"})
+		return
+	}
+
+	result, err := h.db.Exec(
+		"INSERT INTO task_comments (task_id, user_id, body) VALUES (?, ?, ?)",
+		taskID, c.GetInt("userID"), request.Body,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	comment, err := scanComment(h.db.QueryRow(commentSelectSQL+" WHERE c.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// loadOwnComment находит комментарий задачи и проверяет, что его меняет автор или админ
+func (h *CommentHandler) loadOwnComment(c *gin.Context, taskID int) (int, bool) {
+	commentID, err := strconv.Atoi(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return 0, false
+	}
+
+	var authorID int
+	err = h.db.QueryRow("SELECT user_id FROM task_comments WHERE id = ? AND task_id = ?", commentID, taskID).Scan(&authorID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Комментарий не найден"})
+		return 0, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+
+	if authorID != c.GetInt("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Изменять комментарий может только автор или администратор"})
+		return 0, false
+	}
+	return commentID, true
+}
+
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	taskID, ok := requireVisibleTask(h.db, c)
+	if !ok {
+		return
+	}
+	commentID, ok := h.loadOwnComment(c, taskID)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(request.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Комментарий не может быть пустым"})
+		return
+	}
+
+	_, err := h.db.Exec("UPDATE task_comments SET body = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", request.Body, commentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := scanComment(h.db.QueryRow(commentSelectSQL+" WHERE c.id = ?", commentID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	taskID, ok := requireVisibleTask(h.db, c)
+	if !ok {
+		return
+	}
+	commentID, ok := h.loadOwnComment(c, taskID)
+	if !ok {
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM task_comments WHERE id = ?", commentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Комментарий удалён"})
+}
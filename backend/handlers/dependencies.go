@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Задача b (владелец bu) завершена: прогресс 100% или финальный статус workflow
+const blockerDoneSQL = `(b.progress >= 100 OR COALESCE((
+    SELECT ws.is_final FROM workflow_states ws
+    WHERE ws.key = b.status AND ws.department IN (COALESCE(bu.department, ''), '')
+    ORDER BY ws.department DESC LIMIT 1
+), 0))`
+
+// Есть ли у задачи t незавершённые блокирующие задачи (задачи из корзины не блокируют)
+const isBlockedSQL = `EXISTS (
+    SELECT 1 FROM task_dependencies d
+    JOIN tasks b ON b.id = d.blocked_by_id
+    JOIN users bu ON b.user_id = bu.id
+    WHERE d.task_id = t.id AND b.deleted_at IS NULL AND NOT ` + blockerDoneSQL + `
+)`
+
+type dependencyInfo struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Progress int    `json:"progress"`
+	Status   string `json:"status"`
+	Done     bool   `json:"done"`
+}
+
+// splitIDs разбирает результат GROUP_CONCAT в список ID
+func splitIDs(s string) []int {
+	ids := []int{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// openBlockers возвращает ID незавершённых задач, блокирующих taskID
+func openBlockers(q querier, taskID int) ([]int, error) {
+	rows, err := q.Query(`
+        SELECT b.id FROM task_dependencies d
+        JOIN tasks b ON b.id = d.blocked_by_id
+        JOIN users bu ON b.user_id = bu.id
+        WHERE d.task_id = ? AND b.deleted_at IS NULL AND NOT `+blockerDoneSQL, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// createsCycle — появится ли цикл, если taskID станет зависеть от blockerID,
+// то есть достижима ли taskID из blockerID по цепочке blocked_by
+func createsCycle(q querier, taskID, blockerID int) (bool, error) {
+	var found int
+	err := q.QueryRow(`
+        WITH RECURSIVE chain(id) AS (
+            SELECT ?
+            UNION
+            SELECT d.blocked_by_id FROM task_dependencies d JOIN chain ON d.task_id = chain.id
+        )
+        SELECT 1 FROM chain WHERE id = ?
+    `, blockerID, taskID).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// canEditTask повторяет проверку прав UpdateTask: пользователь меняет только свои задачи
+func canEditTask(q querier, c *gin.Context, taskID int) (bool, error) {
+	var ownerID int
+	err := q.QueryRow("SELECT user_id FROM tasks WHERE id = ? AND deleted_at IS NULL", taskID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return c.GetString("userRole") != "user" || ownerID == c.GetInt("userID"), nil
+}
+
+func (h *TaskHandler) listDependencies(query string, taskID int) ([]dependencyInfo, error) {
+	rows, err := h.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []dependencyInfo{}
+	for rows.Next() {
+		var d dependencyInfo
+		if err := rows.Scan(&d.ID, &d.Title, &d.Progress, &d.Status, &d.Done); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func (h *TaskHandler) GetDependencies(c *gin.Context) {
+	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	visible, err := taskReadable(h.db, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Задача не найдена"})
+		return
+	}
+
+	blockedBy, err := h.listDependencies(`
+        SELECT b.id, b.title, b.progress, b.status, `+blockerDoneSQL+`
+        FROM task_dependencies d
+        JOIN tasks b ON b.id = d.blocked_by_id
+        JOIN users bu ON b.user_id = bu.id
+        WHERE d.task_id = ? AND b.deleted_at IS NULL
+        ORDER BY b.id
+    `, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	blocks, err := h.listDependencies(`
+        SELECT b.id, b.title, b.progress, b.status, `+blockerDoneSQL+`
+        FROM task_dependencies d
+        JOIN tasks b ON b.id = d.task_id
+        JOIN users bu ON b.user_id = bu.id
+        WHERE d.blocked_by_id = ? AND b.deleted_at IS NULL
+        ORDER BY b.id
+    `, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blocked_by": blockedBy,
+		"blocks":     blocks,
+	})
+}
+
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	var request struct {
+		BlockedBy int `json:"blocked_by" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.BlockedBy == taskID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Задача не может зависеть от самой себя"})
+		return
+	}
+
+	canEdit, err := canEditTask(h.db, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return
+	}
+
+	visible, err := taskVisible(h.db, c, request.BlockedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Блокирующая задача не найдена"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	cycle, err := createsCycle(tx, taskID, request.BlockedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cycle {
+		c.JSON(http.StatusConflict, gin.H{"error": "Зависимость создаёт цикл"})
+		return
+	}
+
+	_, err = tx.Exec("INSERT OR IGNORE INTO task_dependencies (task_id, blocked_by_id) VALUES (?, ?)", taskID, request.BlockedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Зависимость добавлена", "task_id": taskID, "blocked_by": request.BlockedBy})
+}
+
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	blockerID, err := strconv.Atoi(c.Query("blocked_by"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите blocked_by"})
+		return
+	}
+
+	canEdit, err := canEditTask(h.db, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM task_dependencies WHERE task_id = ? AND blocked_by_id = ?", taskID, blockerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Зависимость не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Зависимость удалена"})
+}
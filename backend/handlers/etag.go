@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskETag — сильный ETag задачи по её версии
+func taskETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagMatches сравнивает значение If-Match со списком тегов; "*" совпадает с любой версией
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch сверяет заголовок If-Match с текущей версией задачи.
+// При расхождении отдаёт 412 и актуальную копию задачи. Без заголовка, для отсутствующей
+// или недоступной задачи проверка пропускается — ответ даст обычная обработка запроса.
+func checkIfMatch(q querier, c *gin.Context, taskID int) (int, gin.H) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+
+	visible, err := taskVisible(q, c, taskID)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if !visible {
+		return 0, nil
+	}
+
+	task, err := loadTask(q, taskID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	etag := taskETag(task.Version)
+	if etagMatches(header, etag) {
+		return 0, nil
+	}
+
+	c.Header("ETag", etag)
+	return http.StatusPreconditionFailed, gin.H{"error": "Задача была изменена другим пользователем", "task": task}
+}
+
+// taskVersion читает текущую версию задачи
+func taskVersion(q querier, taskID int) (int, error) {
+	var version int
+	err := q.QueryRow("SELECT version FROM tasks WHERE id = ?", taskID).Scan(&version)
+	return version, err
+}
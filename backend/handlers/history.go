@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Поля задачи, изменения которых попадают в историю (порядок совпадает с historyColumnsSQL)
+var historyFields = []string{
+	"title", "description", "progress", "hours_per_week", "load_per_month", "status", "priority",
+	"start_date", "due_date", "parent_id", "user_id", "project_id",
+}
+
+const historyColumnsSQL = `title, description, progress, hours_per_week, load_per_month, status, priority,
+    CAST(start_date AS TEXT), CAST(due_date AS TEXT), parent_id, user_id, project_id`
+
+type fieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type historyEntry struct {
+	ID        int                    `json:"id"`
+	TaskID    int                    `json:"task_id"`
+	Action    string                 `json:"action"`
+	UserID    *int                   `json:"user_id"`
+	Username  string                 `json:"username"`
+	Changes   map[string]fieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// taskSnapshot читает отслеживаемые поля задачи
+func taskSnapshot(q querier, taskID int) (map[string]interface{}, error) {
+	values := make([]interface{}, len(historyFields))
+	dest := make([]interface{}, len(historyFields))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := q.QueryRow("SELECT "+historyColumnsSQL+" FROM tasks WHERE id = ?", taskID).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]interface{}, len(historyFields))
+	for i, field := range historyFields {
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		snapshot[field] = values[i]
+	}
+	return snapshot, nil
+}
+
+// recordHistory сохраняет разницу между снимками задачи до и после действия.
+// Для create before == nil, для delete after == nil. Пустая разница не записывается.
+func recordHistory(q querier, taskID, actorID int, action string, before, after map[string]interface{}) error {
+	changes := map[string]fieldChange{}
+	for _, field := range historyFields {
+		var oldValue, newValue interface{}
+		if before != nil {
+			oldValue = before[field]
+		}
+		if after != nil {
+			newValue = after[field]
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[field] = fieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	var actor interface{}
+	if actorID != 0 {
+		actor = actorID
+	}
+	_, err = q.Exec(
+		"INSERT INTO task_history (task_id, user_id, action, changes) VALUES (?, ?, ?, ?)",
+		taskID, actor, action, string(data),
+	)
+	return err
+}
+
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(`
+        SELECT th.id, th.task_id, th.action, th.user_id, COALESCE(u.username, ''), th.changes, th.created_at
+        FROM task_history th
+        LEFT JOIN users u ON th.user_id = u.id
+        WHERE th.task_id = ?
+        ORDER BY th.created_at, th.id
+    `, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	history := []historyEntry{}
+	for rows.Next() {
+		var entry historyEntry
+		var changes string
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &entry.Action, &entry.UserID, &entry.Username, &changes, &entry.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		history = append(history, entry)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
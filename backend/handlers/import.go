@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	maxImportSize = 5 << 20 // 5 МБ
+	maxImportRows = 1000
+)
+
+// importColumns сопоставляет заголовки выгрузок (my_tasks.xlsx, department_tasks.xlsx, all_tasks.xlsx)
+// с полями задачи. Вычисляемые колонки — часы, дата создания, просрочка, отдел, метки — при импорте пропускаются.
+// Колонка ID из выгрузки позволяет обновить задачу, даже если её название изменилось;
+// без неё строка сопоставляется по названию и сотруднику.
+var importColumns = map[string]string{
+	"id":              "id",
+	"название":        "title",
+	"описание":        "description",
+	"описание задачи": "description",
+	"прогресс (%)":    "progress",
+	"прогресс выполнения (%)": "progress",
+	"статус":    "status",
+	"приоритет": "priority",
+	"нагрузка с задачи на месяц (%)":  "load",
+	"нагрузка от задачи на месяц (%)": "load",
+	"срок":      "due",
+	"сотрудник": "employee",
+}
+
+// Форматы дат, в которых Excel может сохранить колонку "Срок"
+var importDateLayouts = []string{dateLayout, "02.01.2006", "01-02-06", "1/2/06"}
+
+// importRowResult — итог обработки строки файла; row — номер строки в файле, считая заголовок
+type importRowResult struct {
+	Row    int    `json:"row"`
+	Action string `json:"action"` // create, update или error
+	TaskID int    `json:"task_id,omitempty"`
+	Title  string `json:"title"`
+	Error  string `json:"error,omitempty"`
+}
+
+// readImportFile читает строки первого листа xlsx или CSV (разделитель — запятая или точка с запятой)
+func readImportFile(ext string, data []byte) ([][]string, error) {
+	if ext == ".xlsx" {
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("в книге нет листов")
+		}
+		return f.GetRows(sheets[0])
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	reader := csv.NewReader(bytes.NewReader(data))
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+func importNumber(value string) (int, error) {
+	number, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(number)), nil
+}
+
+func importDate(value string) (string, bool) {
+	for _, layout := range importDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(dateLayout), true
+		}
+	}
+	return "", false
+}
+
+// importPriority принимает как код приоритета, так и его название из выгрузки
+func importPriority(value string) (string, bool) {
+	for key, name := range priorityNames {
+		if strings.EqualFold(value, key) || strings.EqualFold(value, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// importStatus принимает как ключ состояния workflow, так и его название из выгрузки
+func importStatus(wf models.Workflow, value string) (string, bool) {
+	for _, s := range wf.States {
+		if strings.EqualFold(value, s.Key) || strings.EqualFold(value, s.Name) {
+			return s.Key, true
+		}
+	}
+	return "", false
+}
+
+// applyImportRow переносит значения строки в задачу. Пустые ячейки прогресса, нагрузки, статуса
+// и приоритета оставляют прежние значения, пустой срок его снимает.
+func applyImportRow(q querier, task *models.Task, values map[string]string) string {
+	if title, ok := values["title"]; ok {
+		task.Title = title
+	}
+	if task.Title == "" {
+		return "Название не может быть пустым"
+	}
+	if description, ok := values["description"]; ok {
+		task.Description = description
+	}
+
+	var err error
+	if value := values["progress"]; value != "" {
+		if task.Progress, err = importNumber(value); err != nil {
+			return "Прогресс должен быть числом"
+		}
+	}
+	if value := values["load"]; value != "" {
+		if task.LoadPerMonth, err = importNumber(value); err != nil {
+			return "Нагрузка должна быть числом"
+		}
+	}
+	if value, ok := values["due"]; ok {
+		task.DueDate = ""
+		if value != "" {
+			date, ok := importDate(value)
+			if !ok {
+				return "Срок выполнения должен быть в формате ГГГГ-ММ-ДД"
+			}
+			task.DueDate = date
+		}
+	}
+	if value := values["priority"]; value != "" {
+		priority, ok := importPriority(value)
+		if !ok {
+			return "Неизвестный приоритет: " + value
+		}
+		task.Priority = priority
+	}
+	if value := values["status"]; value != "" {
+		wf, err := taskWorkflow(q, task.UserID)
+		if err != nil {
+			return err.Error()
+		}
+		status, ok := importStatus(wf, value)
+		if !ok {
+			return "Неизвестный статус: " + value
+		}
+		task.Status = status
+	}
+	return ""
+}
+
+// ImportTasks загружает задачи из xlsx или CSV в формате выгрузок (multipart-поле file).
+// Строка обновляет задачу с тем же ID либо с тем же названием у того же сотрудника, иначе создаёт новую.
+// Каждая строка проверяется по правилам UpdateTask; сотрудники — по правилам назначения задач.
+// Файл применяется целиком в одной транзакции: при ошибке в любой строке ничего не сохраняется.
+// С ?dry_run=true изменения только проверяются и не сохраняются.
+func (h *TaskHandler) ImportTasks(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан или превышает допустимый размер"})
+		return
+	}
+	if fileHeader.Size > maxImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Размер файла не может превышать 5 МБ"})
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if ext != ".xlsx" && ext != ".csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Поддерживаются файлы .xlsx и .csv"})
+		return
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := readImportFile(ext, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать файл: " + err.Error()})
+		return
+	}
+	if len(rows) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В файле нет строк с задачами"})
+		return
+	}
+	if len(rows)-1 > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "За один раз можно загрузить не больше " + strconv.Itoa(maxImportRows) + " строк"})
+		return
+	}
+
+	columns := map[int]string{}
+	hasTitle := false
+	for i, header := range rows[0] {
+		header = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(header), ":")))
+		if field, ok := importColumns[header]; ok {
+			columns[i] = field
+			hasTitle = hasTitle || field == "title"
+		}
+	}
+	if !hasTitle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В файле нет колонки \"Название\""})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	results := []importRowResult{}
+	var overloads []models.UserCapacity
+	created, updated, failed := 0, 0, 0
+	for i, row := range rows[1:] {
+		values := map[string]string{}
+		empty := true
+		for j, value := range row {
+			if field, ok := columns[j]; ok {
+				values[field] = strings.TrimSpace(value)
+				empty = empty && values[field] == ""
+			}
+		}
+		if empty {
+			continue
+		}
+
+		// Каждая строка — в своей точке сохранения, чтобы ошибка в ней не затронула остальные
+		if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		result, rowOverloads, err := h.importRow(tx, c, values)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result.Error != "" {
+			if _, err := tx.Exec("ROLLBACK TO import_row"); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if _, err := tx.Exec("RELEASE import_row"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		result.Row = i + 2
+		switch result.Action {
+		case "create":
+			created++
+		case "update":
+			updated++
+		default:
+			failed++
+		}
+		overloads = append(overloads, rowOverloads...)
+		results = append(results, result)
+	}
+
+	response := gin.H{
+		"dry_run":           dryRun,
+		"created":           created,
+		"updated":           updated,
+		"errors":            failed,
+		"rows":              results,
+		"capacity_warnings": overloads,
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if failed > 0 {
+		response["error"] = "В файле есть ошибки, задачи не загружены"
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// importRow создаёт или обновляет задачу по строке файла. Ошибки данных возвращаются в результате строки,
+// error — только для сбоев базы.
+func (h *TaskHandler) importRow(tx *sql.Tx, c *gin.Context, values map[string]string) (importRowResult, []models.UserCapacity, error) {
+	userID := c.GetInt("userID")
+	result := importRowResult{Action: "error", Title: values["title"]}
+	fail := func(msg string) (importRowResult, []models.UserCapacity, error) {
+		result.Error = msg
+		return result, nil, nil
+	}
+
+	// Сотрудник из колонки "Сотрудник", в выгрузке "Мои задачи" её нет — тогда это сам пользователь
+	ownerID := 0
+	if username := values["employee"]; username != "" {
+		err := tx.QueryRow("SELECT id FROM users WHERE username = ?", username).Scan(&ownerID)
+		if err == sql.ErrNoRows {
+			return fail("Сотрудник не найден: " + username)
+		} else if err != nil {
+			return result, nil, err
+		}
+	}
+
+	taskID := 0
+	if value := values["id"]; value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return fail("Некорректный ID: " + value)
+		}
+		scope, args := roleScope(c)
+		var currentOwner int
+		err = tx.QueryRow(`
+            SELECT t.user_id FROM tasks t JOIN users u ON t.user_id = u.id
+            WHERE t.id = ? AND t.deleted_at IS NULL AND `+scope, append([]interface{}{id}, args...)...).Scan(&currentOwner)
+		if err == sql.ErrNoRows {
+			return fail("Задача " + value + " не найдена")
+		} else if err != nil {
+			return result, nil, err
+		}
+		if ownerID != 0 && ownerID != currentOwner {
+			return fail("Сменить сотрудника при импорте нельзя, используйте переназначение задачи")
+		}
+		taskID, ownerID = id, currentOwner
+	} else {
+		if ownerID == 0 {
+			ownerID = userID
+		}
+		if ownerID != userID {
+			if _, msg := checkAssignee(tx, c, ownerID); msg != "" {
+				return fail(msg)
+			}
+		}
+
+		rows, err := tx.Query("SELECT id FROM tasks WHERE title = ? AND user_id = ? AND deleted_at IS NULL", values["title"], ownerID)
+		if err != nil {
+			return result, nil, err
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return result, nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if len(ids) > 1 {
+			return fail("У сотрудника несколько задач с таким названием, укажите ID")
+		}
+		if len(ids) == 1 {
+			taskID = ids[0]
+		}
+	}
+
+	if taskID != 0 {
+		task, err := loadTask(tx, taskID)
+		if err != nil {
+			return result, nil, err
+		}
+		if msg := applyImportRow(tx, &task, values); msg != "" {
+			return fail(msg)
+		}
+		result.Title = task.Title
+
+		overloads, status, body := updateTask(tx, c, taskID, task)
+		if status == http.StatusInternalServerError {
+			return result, nil, fmt.Errorf("%v", body["error"])
+		}
+		if status != 0 {
+			return fail(fmt.Sprint(body["error"]))
+		}
+		result.Action, result.TaskID = "update", taskID
+		return result, overloads, nil
+	}
+
+	task := models.Task{UserID: ownerID, Priority: defaultPriority}
+	if msg := applyImportRow(tx, &task, values); msg != "" {
+		return fail(msg)
+	}
+	if msg := validateTask(task); msg != "" {
+		return fail(msg)
+	}
+	if task.Status == "" {
+		wf, err := taskWorkflow(tx, ownerID)
+		if err != nil {
+			return result, nil, err
+		}
+		task.Status = workflowInitialState(wf)
+	}
+
+	ids, overloads, status, body := createTasks(tx, ownerID, userID, task)
+	if status == http.StatusInternalServerError {
+		return result, nil, fmt.Errorf("%v", body["error"])
+	}
+	if status != 0 {
+		return fail(fmt.Sprint(body["error"]))
+	}
+
+	result.Action, result.TaskID = "create", ids[0]
+	return result, overloads, nil
+}
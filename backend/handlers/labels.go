@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Метки задачи t JSON-массивом, отсортированные по названию
+const taskLabelsSQL = `(
+    SELECT json_group_array(json_object('id', id, 'name', name, 'color', color, 'department', department))
+    FROM (
+        SELECT l.id, l.name, l.color, l.department FROM task_labels tl
+        JOIN labels l ON l.id = tl.label_id
+        WHERE tl.task_id = t.id
+        ORDER BY l.name
+    )
+)`
+
+// Названия меток задачи t через запятую — для выгрузок
+const labelNamesSQL = `COALESCE((
+    SELECT GROUP_CONCAT(name, ', ') FROM (
+        SELECT l.name FROM task_labels tl
+        JOIN labels l ON l.id = tl.label_id
+        WHERE tl.task_id = t.id
+        ORDER BY l.name
+    )
+), '')`
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type LabelHandler struct {
+	db *sql.DB
+}
+
+func NewLabelHandler(db *sql.DB) *LabelHandler {
+	return &LabelHandler{db: db}
+}
+
+func scanLabel(row rowScanner) (models.Label, error) {
+	var label models.Label
+	err := row.Scan(&label.ID, &label.Name, &label.Color, &label.Department)
+	return label, err
+}
+
+// validateLabel нормализует метку и возвращает текст ошибки, либо пустую строку
+func validateLabel(label *models.Label) string {
+	label.Name = strings.TrimSpace(label.Name)
+	if label.Name == "" {
+		return "Название метки не может быть пустым"
+	}
+	if len([]rune(label.Name)) > 50 {
+		return "Название метки не может быть длиннее 50 символов"
+	}
+	if label.Color != "" && !labelColorPattern.MatchString(label.Color) {
+		return "Цвет метки должен быть в формате #RRGGBB"
+	}
+	return ""
+}
+
+// loadLabel находит метку по :id
+func (h *LabelHandler) loadLabel(c *gin.Context) (models.Label, bool) {
+	labelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return models.Label{}, false
+	}
+
+	label, err := scanLabel(h.db.QueryRow("SELECT id, name, color, department FROM labels WHERE id = ?", labelID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Метка не найдена"})
+		return label, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return label, false
+	}
+	return label, true
+}
+
+// canManageLabel — админ управляет любыми метками, менеджер — только метками своего отдела
+func canManageLabel(c *gin.Context, label models.Label) bool {
+	return c.GetString("userRole") == "admin" || label.Department == c.GetString("userDepartment")
+}
+
+// GetLabels возвращает общие метки и метки отдела пользователя; админ видит все
+func (h *LabelHandler) GetLabels(c *gin.Context) {
+	query := "SELECT id, name, color, department FROM labels"
+	var args []interface{}
+	if c.GetString("userRole") != "admin" {
+		query += " WHERE department IN ('', ?)"
+		args = append(args, c.GetString("userDepartment"))
+	}
+	query += " ORDER BY department, name"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	labels := []models.Label{}
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		labels = append(labels, label)
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+func (h *LabelHandler) CreateLabel(c *gin.Context) {
+	var label models.Label
+	if err := c.ShouldBindJSON(&label); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Менеджер создаёт метки своего отдела, админ — любого или общие ("")
+	if c.GetString("userRole") != "admin" {
+		label.Department = c.GetString("userDepartment")
+	}
+	if msg := validateLabel(&label); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	result, err := h.db.Exec("INSERT OR IGNORE INTO labels (name, color, department) VALUES (?, ?, ?)",
+		label.Name, label.Color, label.Department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Метка с таким названием уже существует"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	label.ID = int(id)
+	c.JSON(http.StatusCreated, label)
+}
+
+func (h *LabelHandler) UpdateLabel(c *gin.Context) {
+	label, ok := h.loadLabel(c)
+	if !ok {
+		return
+	}
+	if !canManageLabel(c, label) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return
+	}
+
+	var request models.Label
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Отдел метки не меняется: это заново определило бы, к каким задачам её можно прикрепить
+	request.ID = label.ID
+	request.Department = label.Department
+	if msg := validateLabel(&request); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var exists int
+	err := h.db.QueryRow("SELECT 1 FROM labels WHERE department = ? AND name = ? AND id != ?",
+		request.Department, request.Name, request.ID).Scan(&exists)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Метка с таким названием уже существует"})
+		return
+	} else if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.db.Exec("UPDATE labels SET name = ?, color = ? WHERE id = ?", request.Name, request.Color, request.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *LabelHandler) DeleteLabel(c *gin.Context) {
+	label, ok := h.loadLabel(c)
+	if !ok {
+		return
+	}
+	if !canManageLabel(c, label) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM task_labels WHERE label_id = ?", label.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM labels WHERE id = ?", label.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Метка удалена"})
+}
+
+// requireEditableTask — задача видна пользователю и он может её менять
+func requireEditableTask(q querier, c *gin.Context) (int, bool) {
+	taskID, ok := requireVisibleTask(q, c)
+	if !ok {
+		return 0, false
+	}
+
+	canEdit, err := canEditTask(q, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return 0, false
+	}
+	return taskID, true
+}
+
+// AddTaskLabel прикрепляет к задаче общую метку или метку отдела её исполнителя
+func (h *LabelHandler) AddTaskLabel(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		LabelID int `json:"label_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists int
+	err := h.db.QueryRow(`
+        SELECT 1 FROM labels l, tasks t JOIN users u ON t.user_id = u.id
+        WHERE l.id = ? AND t.id = ? AND l.department IN ('', COALESCE(u.department, ''))
+    `, request.LabelID, taskID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Метка не найдена"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.db.Exec("INSERT OR IGNORE INTO task_labels (task_id, label_id) VALUES (?, ?)", taskID, request.LabelID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Метка добавлена", "task_id": taskID, "label_id": request.LabelID})
+}
+
+func (h *LabelHandler) RemoveTaskLabel(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	labelID, err := strconv.Atoi(c.Param("labelId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM task_labels WHERE task_id = ? AND label_id = ?", taskID, labelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Метка не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Метка снята"})
+}
@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var projectStatuses = []string{"planned", "active", "on_hold", "completed", "cancelled"}
+
+const projectSelectSQL = `
+    SELECT p.id, p.name, p.description, p.status, p.start_date, p.end_date, p.owner_id, COALESCE(o.username, ''),
+           COALESCE(p.created_by, p.owner_id),
+           (SELECT COUNT(*) FROM tasks t WHERE t.project_id = p.id AND t.deleted_at IS NULL),
+           p.created_at, p.updated_at
+    FROM projects p
+    LEFT JOIN users o ON p.owner_id = o.id`
+
+type ProjectHandler struct {
+	db *sql.DB
+}
+
+func NewProjectHandler(db *sql.DB) *ProjectHandler {
+	return &ProjectHandler{db: db}
+}
+
+// projectScope — админ видит все проекты, остальные — те, где они владельцы или участники
+func projectScope(c *gin.Context) (string, []interface{}) {
+	if c.GetString("userRole") == "admin" {
+		return "1 = 1", nil
+	}
+	userID := c.GetInt("userID")
+	return "(p.owner_id = ? OR EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = ?))",
+		[]interface{}{userID, userID}
+}
+
+func projectVisible(q querier, c *gin.Context, projectID int) (bool, error) {
+	scope, args := projectScope(c)
+	var exists int
+	err := q.QueryRow("SELECT 1 FROM projects p WHERE p.id = ? AND "+scope, append([]interface{}{projectID}, args...)...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// projectProgress — прогресс проекта по его задачам. Подзадачи, чей родитель тоже в проекте,
+// уже учтены в прогрессе родителя и отдельно не считаются.
+func projectProgress(q querier, projectID int) (int, error) {
+	rows, err := q.Query(`
+        SELECT t.progress, t.hours_per_week, t.load_per_month FROM tasks t
+        WHERE t.project_id = ? AND t.deleted_at IS NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM tasks pt WHERE pt.id = t.parent_id AND pt.project_id = t.project_id AND pt.deleted_at IS NULL
+          )
+    `, projectID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	progress, _, err := weightedProgress(rows)
+	return progress, err
+}
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var p models.Project
+	var startDate, endDate sql.NullTime
+	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.Status, &startDate, &endDate, &p.OwnerID, &p.OwnerName,
+		&p.CreatedBy, &p.TaskCount, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return p, err
+	}
+
+	if startDate.Valid {
+		p.StartDate = startDate.Time.Format(dateLayout)
+	}
+	if endDate.Valid {
+		p.EndDate = endDate.Time.Format(dateLayout)
+	}
+	return p, nil
+}
+
+func projectMembers(q querier, projectID int) ([]models.ProjectMember, error) {
+	rows, err := q.Query(`
+        SELECT u.id, u.username, COALESCE(u.department, '') FROM project_members pm
+        JOIN users u ON pm.user_id = u.id
+        WHERE pm.project_id = ?
+        ORDER BY u.department, u.username
+    `, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.ProjectMember{}
+	for rows.Next() {
+		var m models.ProjectMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Department); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// addProjectMember добавляет участника; повторное добавление ничего не меняет
+func addProjectMember(q querier, projectID, userID int) error {
+	_, err := q.Exec("INSERT OR IGNORE INTO project_members (project_id, user_id) VALUES (?, ?)", projectID, userID)
+	return err
+}
+
+// validateProject нормализует проект и возвращает текст ошибки, либо пустую строку
+func validateProject(p *models.Project) string {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return "Название проекта не может быть пустым"
+	}
+	valid := false
+	for _, status := range projectStatuses {
+		if p.Status == status {
+			valid = true
+		}
+	}
+	if !valid {
+		return "Статус проекта может быть planned, active, on_hold, completed или cancelled"
+	}
+
+	var start, end time.Time
+	var err error
+	if p.StartDate != "" {
+		if start, err = time.Parse(dateLayout, p.StartDate); err != nil {
+			return "Дата начала должна быть в формате ГГГГ-ММ-ДД"
+		}
+	}
+	if p.EndDate != "" {
+		if end, err = time.Parse(dateLayout, p.EndDate); err != nil {
+			return "Дата окончания должна быть в формате ГГГГ-ММ-ДД"
+		}
+		if p.StartDate != "" && end.Before(start) {
+			return "Дата окончания не может быть раньше даты начала"
+		}
+	}
+	return ""
+}
+
+func (h *ProjectHandler) GetProjects(c *gin.Context) {
+	scope, args := projectScope(c)
+	query := projectSelectSQL + " WHERE " + scope
+	if status := c.Query("status"); status != "" {
+		query += " AND p.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY p.created_at DESC, p.id DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	projects := []models.Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		projects = append(projects, p)
+	}
+	rows.Close()
+
+	for i := range projects {
+		progress, err := projectProgress(h.db, projects[i].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		projects[i].Progress = progress
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// loadProject находит проект по :id в пределах видимости пользователя
+func (h *ProjectHandler) loadProject(c *gin.Context) (models.Project, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return models.Project{}, false
+	}
+
+	scope, args := projectScope(c)
+	p, err := scanProject(h.db.QueryRow(projectSelectSQL+" WHERE p.id = ? AND "+scope, append([]interface{}{id}, args...)...))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return p, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return p, false
+	}
+	return p, true
+}
+
+// loadOwnProject — как loadProject, но менять проект может только владелец или админ
+func (h *ProjectHandler) loadOwnProject(c *gin.Context) (models.Project, bool) {
+	p, ok := h.loadProject(c)
+	if !ok {
+		return p, false
+	}
+	if p.OwnerID != c.GetInt("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Изменять проект может только его владелец или администратор"})
+		return p, false
+	}
+	return p, true
+}
+
+// respondProject отдаёт проект с участниками и прогрессом
+func (h *ProjectHandler) respondProject(c *gin.Context, status, id int) {
+	p, err := scanProject(h.db.QueryRow(projectSelectSQL+" WHERE p.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p.Progress, err = projectProgress(h.db, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p.Members, err = projectMembers(h.db, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, p)
+}
+
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	p, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+	h.respondProject(c, http.StatusOK, p.ID)
+}
+
+// CreateProject создаёт проект; владелец по умолчанию — автор, назначить другого можно по правилам назначения задач
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	p := models.Project{Status: "planned"}
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateProject(&p); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if p.OwnerID == 0 {
+		p.OwnerID = userID
+	} else if p.OwnerID != userID {
+		if status, msg := checkAssignee(h.db, c, p.OwnerID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        INSERT INTO projects (name, description, status, start_date, end_date, owner_id, created_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, p.Name, p.Description, p.Status, nullableDate(p.StartDate), nullableDate(p.EndDate), p.OwnerID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	if err := addProjectMember(tx, int(id), p.OwnerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondProject(c, http.StatusCreated, int(id))
+}
+
+// UpdateProject меняет проект; непереданные поля сохраняют прежние значения
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	current, ok := h.loadOwnProject(c)
+	if !ok {
+		return
+	}
+
+	p := current
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.ID, p.CreatedBy = current.ID, current.CreatedBy
+	if msg := validateProject(&p); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if p.OwnerID != current.OwnerID {
+		if status, msg := checkAssignee(h.db, c, p.OwnerID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        UPDATE projects
+        SET name = ?, description = ?, status = ?, start_date = ?, end_date = ?, owner_id = ?, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?
+    `, p.Name, p.Description, p.Status, nullableDate(p.StartDate), nullableDate(p.EndDate), p.OwnerID, p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := addProjectMember(tx, p.ID, p.OwnerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondProject(c, http.StatusOK, p.ID)
+}
+
+// DeleteProject удаляет проект; его задачи остаются, но больше к проекту не относятся
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	p, ok := h.loadOwnProject(c)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE tasks SET project_id = NULL, version = version + 1 WHERE project_id = ?", p.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM project_members WHERE project_id = ?", p.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM projects WHERE id = ?", p.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Проект удалён"})
+}
+
+// AddProjectMember добавляет в проект сотрудника любого отдела
+func (h *ProjectHandler) AddProjectMember(c *gin.Context) {
+	p, ok := h.loadOwnProject(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		UserID int `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists int
+	err := h.db.QueryRow("SELECT 1 FROM users WHERE id = ?", request.UserID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := addProjectMember(h.db, p.ID, request.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondProject(c, http.StatusOK, p.ID)
+}
+
+func (h *ProjectHandler) RemoveProjectMember(c *gin.Context) {
+	p, ok := h.loadOwnProject(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if userID == p.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Владельца нельзя исключить из проекта"})
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM project_members WHERE project_id = ? AND user_id = ?", p.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Участник не найден"})
+		return
+	}
+
+	h.respondProject(c, http.StatusOK, p.ID)
+}
+
+// GetProjectTasks возвращает все задачи проекта: участникам видны задачи коллег из других отделов
+func (h *ProjectHandler) GetProjectTasks(c *gin.Context) {
+	p, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(taskSelectSQL+`
+        WHERE t.project_id = ? AND t.deleted_at IS NULL
+        ORDER BY `+reportOrderSQL, p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// SetTaskProject включает задачу в проект ({"project_id": 5}) или исключает из него ({"project_id": null}).
+// Исполнитель задачи становится участником проекта.
+func (h *ProjectHandler) SetTaskProject(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		ProjectID *int `json:"project_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if status, body := setTaskProject(tx, c, taskID, request.ProjectID); status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Проект задачи изменён", "task_id": taskID, "project_id": request.ProjectID})
+}
+
+// setTaskProject проверяет доступ к проекту, меняет project_id задачи и пишет историю.
+// Возвращает HTTP-статус и тело ошибки, либо 0.
+func setTaskProject(q querier, c *gin.Context, taskID int, projectID *int) (int, gin.H) {
+	var ownerID int
+	if err := q.QueryRow("SELECT user_id FROM tasks WHERE id = ?", taskID).Scan(&ownerID); err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	if projectID != nil {
+		visible, err := projectVisible(q, c, *projectID)
+		if err != nil {
+			return http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		if !visible {
+			return http.StatusNotFound, gin.H{"error": "Проект не найден"}
+		}
+		if err := addProjectMember(q, *projectID, ownerID); err != nil {
+			return http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+	}
+
+	before, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	_, err = q.Exec("UPDATE tasks SET project_id = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", projectID, taskID)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	after, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if err := recordHistory(q, taskID, c.GetInt("userID"), "update", before, after); err != nil {
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	return 0, nil
+}
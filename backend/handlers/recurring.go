@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Как часто планировщик проверяет, не пора ли создать очередные повторения
+const recurringCheckInterval = time.Hour
+
+type RecurringHandler struct {
+	db *sql.DB
+}
+
+func NewRecurringHandler(db *sql.DB) *RecurringHandler {
+	return &RecurringHandler{db: db}
+}
+
+// recurrenceRule — расписание шаблона: каждые interval дней/недель/месяцев начиная со start, не позже end
+type recurrenceRule struct {
+	frequency string
+	interval  int
+	start     time.Time
+	end       time.Time // нулевая — без даты окончания
+}
+
+// occurrence возвращает k-е повторение (k = 0 — дата начала).
+// Ежемесячное повторение с 31-го числа в коротких месяцах приходится на последний день месяца.
+func (r recurrenceRule) occurrence(k int) time.Time {
+	switch r.frequency {
+	case "daily":
+		return r.start.AddDate(0, 0, k*r.interval)
+	case "weekly":
+		return r.start.AddDate(0, 0, 7*k*r.interval)
+	default:
+		first := time.Date(r.start.Year(), r.start.Month()+time.Month(k*r.interval), 1, 0, 0, 0, 0, time.UTC)
+		day := r.start.Day()
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return first.AddDate(0, 0, day-1)
+	}
+}
+
+func (r recurrenceRule) ended(date time.Time) bool {
+	return !r.end.IsZero() && date.After(r.end)
+}
+
+// latest возвращает последнее повторение не позже today
+func (r recurrenceRule) latest(today time.Time) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for k := 0; ; k++ {
+		date := r.occurrence(k)
+		if date.After(today) || r.ended(date) {
+			break
+		}
+		latest, found = date, true
+	}
+	return latest, found
+}
+
+// next возвращает первое повторение не раньше today и позже lastRun
+func (r recurrenceRule) next(today, lastRun time.Time) (time.Time, bool) {
+	for k := 0; ; k++ {
+		date := r.occurrence(k)
+		if r.ended(date) {
+			return time.Time{}, false
+		}
+		if !date.Before(today) && date.After(lastRun) {
+			return date, true
+		}
+	}
+}
+
+// localDate — сегодняшняя дата по местному времени (как date('now', 'localtime') в SQL)
+func localDate(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+const recurringSelectSQL = `
+    SELECT t.id, t.title, COALESCE(t.description, ''), t.hours_per_week, t.load_per_month, t.priority,
+           t.frequency, t.interval, t.start_date, t.end_date, t.due_in_days, t.active, t.last_run_date,
+           t.user_id, COALESCE(t.created_by, t.user_id), u.username, COALESCE(u.department, ''), t.created_at
+    FROM recurring_tasks t
+    JOIN users u ON t.user_id = u.id`
+
+// scanRecurring читает шаблон и вычисляет дату следующего повторения
+func scanRecurring(row rowScanner) (models.RecurringTask, error) {
+	var rt models.RecurringTask
+	var startDate time.Time
+	var endDate, lastRun sql.NullTime
+	var dueInDays sql.NullInt64
+
+	err := row.Scan(&rt.ID, &rt.Title, &rt.Description, &rt.HoursPerWeek, &rt.LoadPerMonth, &rt.Priority,
+		&rt.Frequency, &rt.Interval, &startDate, &endDate, &dueInDays, &rt.Active, &lastRun,
+		&rt.UserID, &rt.CreatedBy, &rt.Username, &rt.Department, &rt.CreatedAt)
+	if err != nil {
+		return rt, err
+	}
+
+	rt.StartDate = startDate.Format(dateLayout)
+	rule := recurrenceRule{frequency: rt.Frequency, interval: rt.Interval, start: startDate}
+	if endDate.Valid {
+		rt.EndDate = endDate.Time.Format(dateLayout)
+		rule.end = endDate.Time
+	}
+	if dueInDays.Valid {
+		days := int(dueInDays.Int64)
+		rt.DueInDays = &days
+	}
+	if lastRun.Valid {
+		rt.LastRunDate = lastRun.Time.Format(dateLayout)
+	}
+	if rt.Active {
+		if next, ok := rule.next(localDate(time.Now()), lastRun.Time); ok {
+			rt.NextRunDate = next.Format(dateLayout)
+		}
+	}
+
+	return rt, nil
+}
+
+// validateRecurring проверяет шаблон и возвращает текст ошибки, либо пустую строку
+func validateRecurring(rt models.RecurringTask) string {
+	if rt.Title == "" {
+		return "Название не может быть пустым"
+	}
+	if msg := validateTask(models.Task{HoursPerWeek: rt.HoursPerWeek, LoadPerMonth: rt.LoadPerMonth, Priority: rt.Priority}); msg != "" {
+		return msg
+	}
+	if rt.Frequency != "daily" && rt.Frequency != "weekly" && rt.Frequency != "monthly" {
+		return "frequency может быть daily, weekly или monthly"
+	}
+	if rt.Interval < 1 {
+		return "Интервал должен быть не меньше 1"
+	}
+
+	start, err := time.Parse(dateLayout, rt.StartDate)
+	if err != nil {
+		return "Дата начала должна быть в формате ГГГГ-ММ-ДД"
+	}
+	if rt.EndDate != "" {
+		end, err := time.Parse(dateLayout, rt.EndDate)
+		if err != nil {
+			return "Дата окончания должна быть в формате ГГГГ-ММ-ДД"
+		}
+		if end.Before(start) {
+			return "Дата окончания не может быть раньше даты начала"
+		}
+	}
+	if rt.DueInDays != nil && *rt.DueInDays < 0 {
+		return "Срок не может быть отрицательным"
+	}
+	return ""
+}
+
+func (h *RecurringHandler) GetRecurringTasks(c *gin.Context) {
+	// Видимость как у задач: админ — все, менеджер — отдел, пользователь — свои
+	scope, args := roleScope(c)
+
+	rows, err := h.db.Query(recurringSelectSQL+" WHERE "+scope+" ORDER BY t.id", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	list := []models.RecurringTask{}
+	for rows.Next() {
+		rt, err := scanRecurring(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		list = append(list, rt)
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *RecurringHandler) CreateRecurringTask(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	rt := models.RecurringTask{Interval: 1}
+	if err := c.ShouldBindJSON(&rt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rt.Priority == "" {
+		rt.Priority = defaultPriority
+	}
+	if msg := validateRecurring(rt); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// Владелец шаблона — исполнитель будущих задач; правила назначения как у обычных задач
+	ownerID := userID
+	if rt.AssigneeID != 0 && rt.AssigneeID != userID {
+		if status, msg := checkAssignee(h.db, c, rt.AssigneeID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		ownerID = rt.AssigneeID
+	}
+
+	result, err := h.db.Exec(`
+        INSERT INTO recurring_tasks (title, description, hours_per_week, load_per_month, priority,
+                                     frequency, interval, start_date, end_date, due_in_days, user_id, created_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, rt.Title, rt.Description, rt.HoursPerWeek, rt.LoadPerMonth, rt.Priority,
+		rt.Frequency, rt.Interval, rt.StartDate, nullableDate(rt.EndDate), rt.DueInDays, ownerID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	created, err := scanRecurring(h.db.QueryRow(recurringSelectSQL+" WHERE t.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// loadRecurring находит шаблон по :id в пределах видимости пользователя
+func (h *RecurringHandler) loadRecurring(c *gin.Context) (models.RecurringTask, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring task ID"})
+		return models.RecurringTask{}, false
+	}
+
+	scope, args := roleScope(c)
+	rt, err := scanRecurring(h.db.QueryRow(recurringSelectSQL+" WHERE t.id = ? AND "+scope, append([]interface{}{id}, args...)...))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Повторяющаяся задача не найдена"})
+		return rt, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return rt, false
+	}
+	return rt, true
+}
+
+// UpdateRecurringTask меняет шаблон; непереданные поля сохраняют прежние значения.
+// Уже созданные задачи не меняются.
+func (h *RecurringHandler) UpdateRecurringTask(c *gin.Context) {
+	current, ok := h.loadRecurring(c)
+	if !ok {
+		return
+	}
+
+	rt := current
+	if err := c.ShouldBindJSON(&rt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rt.ID, rt.UserID = current.ID, current.UserID
+	if msg := validateRecurring(rt); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// Смена владельца — по тем же правилам, что и при создании
+	ownerID := rt.UserID
+	if rt.AssigneeID != 0 && rt.AssigneeID != rt.UserID {
+		if status, msg := checkAssignee(h.db, c, rt.AssigneeID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		ownerID = rt.AssigneeID
+	}
+
+	_, err := h.db.Exec(`
+        UPDATE recurring_tasks
+        SET title = ?, description = ?, hours_per_week = ?, load_per_month = ?, priority = ?,
+            frequency = ?, interval = ?, start_date = ?, end_date = ?, due_in_days = ?, active = ?, user_id = ?
+        WHERE id = ?
+    `, rt.Title, rt.Description, rt.HoursPerWeek, rt.LoadPerMonth, rt.Priority,
+		rt.Frequency, rt.Interval, rt.StartDate, nullableDate(rt.EndDate), rt.DueInDays, rt.Active, ownerID, rt.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := scanRecurring(h.db.QueryRow(recurringSelectSQL+" WHERE t.id = ?", rt.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteRecurringTask удаляет шаблон; созданные из него задачи остаются
+func (h *RecurringHandler) DeleteRecurringTask(c *gin.Context) {
+	rt, ok := h.loadRecurring(c)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE tasks SET recurring_id = NULL WHERE recurring_id = ?", rt.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM recurring_tasks WHERE id = ?", rt.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Повторяющаяся задача удалена"})
+}
+
+// materializeRecurring создаёт задачу для последнего наступившего повторения шаблона, если её ещё нет.
+// Пропущенные, пока сервер не работал, повторения не досоздаются — появляется только актуальное.
+// Повторный вызов безопасен: уникальный индекс (recurring_id, occurrence_date) не даст создать дубль.
+func materializeRecurring(db *sql.DB, rt models.RecurringTask, today time.Time) (bool, error) {
+	start, err := time.Parse(dateLayout, rt.StartDate)
+	if err != nil {
+		return false, err
+	}
+	rule := recurrenceRule{frequency: rt.Frequency, interval: rt.Interval, start: start}
+	if rt.EndDate != "" {
+		if rule.end, err = time.Parse(dateLayout, rt.EndDate); err != nil {
+			return false, err
+		}
+	}
+
+	occurrence, ok := rule.latest(today)
+	if !ok {
+		return false, nil
+	}
+	if rt.LastRunDate != "" {
+		lastRun, err := time.Parse(dateLayout, rt.LastRunDate)
+		if err != nil {
+			return false, err
+		}
+		if !occurrence.After(lastRun) {
+			return false, nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	wf, err := taskWorkflow(tx, rt.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	var dueDate interface{}
+	if rt.DueInDays != nil {
+		dueDate = occurrence.AddDate(0, 0, *rt.DueInDays).Format(dateLayout)
+	}
+
+	result, err := tx.Exec(`
+        INSERT OR IGNORE INTO tasks (title, description, hours_per_week, load_per_month, status, priority,
+                                     start_date, due_date, user_id, created_by, recurring_id, occurrence_date)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, rt.Title, rt.Description, rt.HoursPerWeek, rt.LoadPerMonth, workflowInitialState(wf), rt.Priority,
+		occurrence.Format(dateLayout), dueDate, rt.UserID, rt.CreatedBy, rt.ID, occurrence.Format(dateLayout))
+	if err != nil {
+		return false, err
+	}
+
+	created := false
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+		id, _ := result.LastInsertId()
+		after, err := taskSnapshot(tx, int(id))
+		if err != nil {
+			return false, err
+		}
+		if err := recordHistory(tx, int(id), 0, "create", nil, after); err != nil {
+			return false, err
+		}
+		created = true
+	}
+
+	if _, err := tx.Exec("UPDATE recurring_tasks SET last_run_date = ? WHERE id = ?", occurrence.Format(dateLayout), rt.ID); err != nil {
+		return false, err
+	}
+
+	return created, tx.Commit()
+}
+
+// GenerateRecurringTasks создаёт задачи по всем активным шаблонам, у которых наступило очередное повторение
+func GenerateRecurringTasks(db *sql.DB, now time.Time) (int, error) {
+	rows, err := db.Query(recurringSelectSQL + " WHERE t.active = 1 ORDER BY t.id")
+	if err != nil {
+		return 0, err
+	}
+	var list []models.RecurringTask
+	for rows.Next() {
+		rt, err := scanRecurring(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		list = append(list, rt)
+	}
+	rows.Close()
+
+	today := localDate(now)
+	created := 0
+	for _, rt := range list {
+		ok, err := materializeRecurring(db, rt, today)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// StartRecurringScheduler раз в час создаёт задачи по шаблонам повторяющихся задач
+func StartRecurringScheduler(db *sql.DB) {
+	go func() {
+		for {
+			created, err := GenerateRecurringTasks(db, time.Now())
+			if err != nil {
+				log.Printf("Recurring tasks generation failed: %v", err)
+			} else if created > 0 {
+				log.Printf("Created %d recurring tasks", created)
+			}
+			time.Sleep(recurringCheckInterval)
+		}
+	}()
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"strconv"
+	"task-management-backend/models"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,13 +19,243 @@ func NewReportHandler(db *sql.DB) *ReportHandler {
 	return &ReportHandler{db: db}
 }
 
+// Колонка "Просрочена" во всех выгрузках задач
+const overdueColumn = 10
+
+// Порядок строк в выгрузках: сначала важные, затем ближайший срок
+const reportOrderSQL = priorityRankSQL + ` DESC, COALESCE(t.due_date, '9999-12-31'), t.created_at DESC, t.id`
+
+var priorityNames = map[string]string{
+	"low":      "Низкий",
+	"normal":   "Обычный",
+	"high":     "Высокий",
+	"critical": "Критический",
+}
+
+// Заливка строк по приоритету; у обычных задач заливки нет
+var priorityFills = map[string]string{
+	"low":      "EDEDED",
+	"high":     "FFE699",
+	"critical": "F8CBAD",
+}
+
+func priorityName(priority string) string {
+	if name, ok := priorityNames[priority]; ok {
+		return name
+	}
+	return priority
+}
+
+// reportPeriod читает ?from= и ?to= (ГГГГ-ММ-ДД) — период, за который считаются учтённые часы.
+// Без параметров берётся всё время.
+func reportPeriod(c *gin.Context) (string, string, bool) {
+	from, to := c.DefaultQuery("from", "0001-01-01"), c.DefaultQuery("to", "9999-12-31")
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from должен быть в формате ГГГГ-ММ-ДД"})
+		return "", "", false
+	}
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to должен быть в формате ГГГГ-ММ-ДД"})
+		return "", "", false
+	}
+	if toDate.Before(fromDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Дата окончания периода раньше даты начала"})
+		return "", "", false
+	}
+	return from, to, true
+}
+
+func formatDate(date sql.NullTime) string {
+	if !date.Valid {
+		return ""
+	}
+	return date.Time.Format("2006-01-02")
+}
+
+func overdueMark(overdue bool) string {
+	if overdue {
+		return "Да"
+	}
+	return ""
+}
+
+// cellStyle — оформление ячейки выгрузки: заливка по приоритету, отступ подзадачи, отметка о просрочке
+type cellStyle struct {
+	fill    string
+	indent  int
+	overdue bool
+}
+
+// newCellStyle создаёт стиль excelize; просрочка — красная заливка поверх заливки приоритета
+func newCellStyle(f *excelize.File, s cellStyle) int {
+	style := &excelize.Style{}
+	if s.fill != "" {
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{s.fill}, Pattern: 1}
+	}
+	if s.overdue {
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{"FFC7CE"}, Pattern: 1}
+		style.Font = &excelize.Font{Bold: true, Color: "9C0006"}
+	}
+	if s.indent > 0 {
+		style.Alignment = &excelize.Alignment{Indent: s.indent}
+	}
+	id, _ := f.NewStyle(style)
+	return id
+}
+
+// reportRow — строка выгрузки задач; первая колонка data — название задачи
+type reportRow struct {
+	id       int
+	parentID sql.NullInt64
+	data     []interface{}
+	priority string
+	overdue  bool
+	depth    int
+}
+
+// orderByTree выстраивает строки так, чтобы подзадачи шли сразу под родителем.
+// Задачи, чей родитель не попал в выгрузку, считаются задачами верхнего уровня.
+func orderByTree(rows []reportRow) []reportRow {
+	present := map[int]bool{}
+	for _, r := range rows {
+		present[r.id] = true
+	}
+
+	children := map[int][]reportRow{}
+	var roots []reportRow
+	for _, r := range rows {
+		if r.parentID.Valid && present[int(r.parentID.Int64)] {
+			children[int(r.parentID.Int64)] = append(children[int(r.parentID.Int64)], r)
+		} else {
+			roots = append(roots, r)
+		}
+	}
+
+	ordered := make([]reportRow, 0, len(rows))
+	visited := map[int]bool{}
+	var walk func(r reportRow, depth int)
+	walk = func(r reportRow, depth int) {
+		if visited[r.id] {
+			return
+		}
+		visited[r.id] = true
+		r.depth = depth
+		ordered = append(ordered, r)
+		for _, child := range children[r.id] {
+			walk(child, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	return ordered
+}
+
+// writeTaskSheet записывает заголовки и строки задач на лист: подзадачи — с отступом под родителем,
+// строки подсвечены по приоритету, просроченные отмечены красным
+func writeTaskSheet(f *excelize.File, sheet string, headers []string, rows []reportRow) {
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, header)
+	}
+
+	styles := map[cellStyle]int{}
+	styleFor := func(s cellStyle) int {
+		id, ok := styles[s]
+		if !ok {
+			id = newCellStyle(f, s)
+			styles[s] = id
+		}
+		return id
+	}
+
+	rowIndex := 2
+	for _, r := range orderByTree(rows) {
+		for i, value := range r.data {
+			cell, _ := excelize.CoordinatesToCellName(i+1, rowIndex)
+			f.SetCellValue(sheet, cell, value)
+
+			s := cellStyle{fill: priorityFills[r.priority]}
+			if i == 0 {
+				s.indent = r.depth * 2
+			}
+			if i+1 == overdueColumn && r.overdue {
+				s.overdue = true
+			}
+			if s != (cellStyle{}) {
+				f.SetCellStyle(sheet, cell, cell, styleFor(s))
+			}
+		}
+		rowIndex++
+	}
+}
+
+// writeHoursSheet добавляет лист с часами, учтёнными сотрудниками за период.
+// scope — условие на te (записи) и u (их авторы).
+func (h *ReportHandler) writeHoursSheet(f *excelize.File, from, to, scope string, args ...interface{}) error {
+	rows, err := h.db.Query(`
+        SELECT u.username, COALESCE(u.department, ''), ROUND(SUM(te.minutes) / 60.0, 2), COUNT(DISTINCT te.task_id)
+        FROM time_entries te
+        JOIN users u ON te.user_id = u.id
+        JOIN tasks t ON t.id = te.task_id AND t.deleted_at IS NULL
+        WHERE te.entry_date BETWEEN ? AND ? AND `+scope+`
+        GROUP BY u.id
+        ORDER BY u.department, u.username
+    `, append([]interface{}{from, to}, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var data [][]interface{}
+	for rows.Next() {
+		var username, department string
+		var hours float64
+		var taskCount int
+		if err := rows.Scan(&username, &department, &hours, &taskCount); err != nil {
+			return err
+		}
+		data = append(data, []interface{}{username, department, hours, taskCount})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	const sheet = "Часы по сотрудникам"
+	f.NewSheet(sheet)
+	writeSheetRows(f, sheet, []string{"Сотрудник", "Отдел", "Часов учтено", "Задач"}, data)
+	return nil
+}
+
+// writeSheetRows записывает на лист заголовки и строки без оформления
+func writeSheetRows(f *excelize.File, sheet string, headers []string, rows [][]interface{}) {
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, header)
+	}
+	for r, row := range rows {
+		for i, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(i+1, r+2)
+			f.SetCellValue(sheet, cell, value)
+		}
+	}
+}
+
 func (h *ReportHandler) ExportMyTasks(c *gin.Context) {
 	userID := c.GetInt("userID")
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
 
 	rows, err := h.db.Query(`
-        SELECT title, description, progress, hours_per_week, load_per_month, created_at
-        FROM tasks WHERE user_id = ?
-    `, userID)
+        SELECT t.id, t.parent_id, t.title, t.description, t.progress, `+statusNameSQL+`, t.priority, `+periodHoursSQL+`, t.load_per_month, t.created_at,
+               t.due_date, `+overdueSQL+`, `+labelNamesSQL+`
+        FROM tasks t JOIN users u ON t.user_id = u.id WHERE t.user_id = ? AND t.deleted_at IS NULL
+        ORDER BY `+reportOrderSQL+`
+    `, from, to, userID)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -35,32 +267,33 @@ func (h *ReportHandler) ExportMyTasks(c *gin.Context) {
 	f.SetSheetName("Sheet1", "Мои задачи")
 
 	// Заголовки
-	headers := []string{"Название", "Описание", "Прогресс (%)", "Часов потрачено", "Нагрузка с задачи на месяц (%)", "Создана"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue("Мои задачи", cell, header)
-	}
+	headers := []string{"Название", "Описание", "Прогресс (%)", "Статус", "Приоритет", "Часов потрачено", "Нагрузка с задачи на месяц (%)", "Создана", "Срок", "Просрочена", "Метки", "ID"}
 
 	// Данные
-	rowIndex := 2
+	var report []reportRow
 	for rows.Next() {
-		var title, description string
+		var r reportRow
+		var title, description, status, labels string
 		var progress, loadPerMonth int
-		var hoursPerWeek float64
+		var hoursSpent float64
 		var createdAt time.Time
+		var dueDate sql.NullTime
 
-		err := rows.Scan(&title, &description, &progress, &hoursPerWeek, &loadPerMonth, &createdAt)
+		err := rows.Scan(&r.id, &r.parentID, &title, &description, &progress, &status, &r.priority, &hoursSpent, &loadPerMonth, &createdAt, &dueDate, &r.overdue, &labels)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		data := []interface{}{title, description, progress, hoursPerWeek, loadPerMonth, createdAt.Format("2006-01-02")}
-		for i, value := range data {
-			cell, _ := excelize.CoordinatesToCellName(i+1, rowIndex)
-			f.SetCellValue("Мои задачи", cell, value)
-		}
-		rowIndex++
+		r.data = []interface{}{title, description, progress, status, priorityName(r.priority), hoursSpent, loadPerMonth,
+			createdAt.Format("2006-01-02"), formatDate(dueDate), overdueMark(r.overdue), labels, r.id}
+		report = append(report, r)
+	}
+
+	writeTaskSheet(f, "Мои задачи", headers, report)
+	if err := h.writeHoursSheet(f, from, to, "te.user_id = ?", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
@@ -70,9 +303,13 @@ func (h *ReportHandler) ExportMyTasks(c *gin.Context) {
 
 func (h *ReportHandler) ExportDepartmentTasks(c *gin.Context) {
 	userDepartment := c.GetString("userDepartment")
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
 
 	rows, err := h.db.Query(`
-        SELECT t.title, t.description, t.progress, t.hours_per_week, t.load_per_month, t.created_at, u.username FROM tasks t JOIN users u ON t.user_id = u.id WHERE u.department = ?`, userDepartment)
+        SELECT t.id, t.parent_id, t.title, t.description, t.progress, `+statusNameSQL+`, t.priority, `+periodHoursSQL+`, t.load_per_month, t.created_at, t.due_date, `+overdueSQL+`, u.username, `+labelNamesSQL+` FROM tasks t JOIN users u ON t.user_id = u.id WHERE u.department = ? AND t.deleted_at IS NULL ORDER BY `+reportOrderSQL, from, to, userDepartment)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -83,32 +320,32 @@ func (h *ReportHandler) ExportDepartmentTasks(c *gin.Context) {
 	f := excelize.NewFile()
 	f.SetSheetName("Sheet1", "Задания отдела")
 
-	headers := []string{"Название", "Описание задачи", "Прогресс выполнения (%)", "Часов потрачено", "Нагрузка от задачи на месяц (%)", "Создана: ", "Сотрудник"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue("Задания отдела", cell, header)
-	}
+	headers := []string{"Название", "Описание задачи", "Прогресс выполнения (%)", "Статус", "Приоритет", "Часов потрачено", "Нагрузка от задачи на месяц (%)", "Создана: ", "Срок", "Просрочена", "Сотрудник", "Метки", "ID"}
 
-	rowIndex := 2
+	var report []reportRow
 	for rows.Next() {
-		var title, description, username string
+		var r reportRow
+		var title, description, status, username, labels string
 		var progress, loadPerMonth int
-		var hoursPerWeek float64
+		var hoursSpent float64
 		var createdAt time.Time
+		var dueDate sql.NullTime
 
-		err := rows.Scan(&title, &description, &progress, &hoursPerWeek, &loadPerMonth, &createdAt, &username)
+		err := rows.Scan(&r.id, &r.parentID, &title, &description, &progress, &status, &r.priority, &hoursSpent, &loadPerMonth, &createdAt, &dueDate, &r.overdue, &username, &labels)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		data := []interface{}{title, description, progress, hoursPerWeek, loadPerMonth,
-			createdAt.Format("2006-01-02"), username}
-		for i, value := range data {
-			cell, _ := excelize.CoordinatesToCellName(i+1, rowIndex)
-			f.SetCellValue("Задания отдела", cell, value)
-		}
-		rowIndex++
+		r.data = []interface{}{title, description, progress, status, priorityName(r.priority), hoursSpent, loadPerMonth,
+			createdAt.Format("2006-01-02"), formatDate(dueDate), overdueMark(r.overdue), username, labels, r.id}
+		report = append(report, r)
+	}
+
+	writeTaskSheet(f, "Задания отдела", headers, report)
+	if err := h.writeHoursSheet(f, from, to, "u.department = ?", userDepartment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
@@ -116,9 +353,83 @@ func (h *ReportHandler) ExportDepartmentTasks(c *gin.Context) {
 	f.Write(c.Writer)
 }
 
+// ExportProjectTasks выгружает задачи проекта ?project_id= из всех отделов; доступно участникам проекта
+func (h *ReportHandler) ExportProjectTasks(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Query("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите project_id"})
+		return
+	}
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
+
+	scope, args := projectScope(c)
+	var projectName string
+	err = h.db.QueryRow("SELECT p.name FROM projects p WHERE p.id = ? AND "+scope, append([]interface{}{projectID}, args...)...).Scan(&projectName)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := h.db.Query(`
+        SELECT t.id, t.parent_id, t.title, t.description, t.progress, `+statusNameSQL+`, t.priority, `+periodHoursSQL+`, t.load_per_month, t.created_at, t.due_date, `+overdueSQL+`, u.username, u.department, `+labelNamesSQL+` FROM tasks t JOIN users u ON t.user_id = u.id WHERE t.project_id = ? AND t.deleted_at IS NULL ORDER BY `+reportOrderSQL, from, to, projectID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "Задачи проекта")
+
+	headers := []string{"Название", "Описание задачи", "Прогресс выполнения (%)", "Статус", "Приоритет", "Часов потрачено", "Нагрузка от задачи на месяц (%)", "Создано", "Срок", "Просрочена", "Сотрудник", "Отдел", "Метки", "ID"}
+
+	var report []reportRow
+	for rows.Next() {
+		var r reportRow
+		var title, description, status, username, department, labels string
+		var progress, loadPerMonth int
+		var hoursSpent float64
+		var createdAt time.Time
+		var dueDate sql.NullTime
+
+		err := rows.Scan(&r.id, &r.parentID, &title, &description, &progress, &status, &r.priority, &hoursSpent, &loadPerMonth,
+			&createdAt, &dueDate, &r.overdue, &username, &department, &labels)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		r.data = []interface{}{title, description, progress, status, priorityName(r.priority), hoursSpent, loadPerMonth,
+			createdAt.Format("2006-01-02"), formatDate(dueDate), overdueMark(r.overdue), username, department, labels, r.id}
+		report = append(report, r)
+	}
+
+	writeTaskSheet(f, "Задачи проекта", headers, report)
+	if err := h.writeHoursSheet(f, from, to, "t.project_id = ?", projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename=project_"+strconv.Itoa(projectID)+"_tasks.xlsx")
+	f.Write(c.Writer)
+}
+
 func (h *ReportHandler) ExportAllTasks(c *gin.Context) {
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
+
 	rows, err := h.db.Query(`
-        SELECT t.title, t.description, t.progress, t.hours_per_week, t.load_per_month, t.created_at, u.username, u.department FROM tasks t JOIN users u ON t.user_id = u.id`)
+        SELECT t.id, t.parent_id, t.title, t.description, t.progress, `+statusNameSQL+`, t.priority, `+periodHoursSQL+`, t.load_per_month, t.created_at, t.due_date, `+overdueSQL+`, u.username, u.department, `+labelNamesSQL+` FROM tasks t JOIN users u ON t.user_id = u.id WHERE t.deleted_at IS NULL ORDER BY `+reportOrderSQL, from, to)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -129,36 +440,113 @@ func (h *ReportHandler) ExportAllTasks(c *gin.Context) {
 	f := excelize.NewFile()
 	f.SetSheetName("Sheet1", "Все задачи")
 
-	headers := []string{"Название", "Описание задачи", "Прогресс выполнения (%)", "Часов потрачено", "Нагрузка от задачи на месяц (%)", "Создано", "Сотрудник", "Отдел"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue("Все задачи", cell, header)
-	}
+	headers := []string{"Название", "Описание задачи", "Прогресс выполнения (%)", "Статус", "Приоритет", "Часов потрачено", "Нагрузка от задачи на месяц (%)", "Создано", "Срок", "Просрочена", "Сотрудник", "Отдел", "Метки", "ID"}
 
-	rowIndex := 2
+	var report []reportRow
 	for rows.Next() {
-		var title, description, username, department string
+		var r reportRow
+		var title, description, status, username, department, labels string
 		var progress, loadPerMonth int
-		var hoursPerWeek float64
+		var hoursSpent float64
 		var createdAt time.Time
+		var dueDate sql.NullTime
 
-		err := rows.Scan(&title, &description, &progress, &hoursPerWeek, &loadPerMonth,
-			&createdAt, &username, &department)
+		err := rows.Scan(&r.id, &r.parentID, &title, &description, &progress, &status, &r.priority, &hoursSpent, &loadPerMonth,
+			&createdAt, &dueDate, &r.overdue, &username, &department, &labels)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		data := []interface{}{title, description, progress, hoursPerWeek, loadPerMonth,
-			createdAt.Format("2006-01-02"), username, department}
-		for i, value := range data {
-			cell, _ := excelize.CoordinatesToCellName(i+1, rowIndex)
-			f.SetCellValue("Все задачи", cell, value)
-		}
-		rowIndex++
+		r.data = []interface{}{title, description, progress, status, priorityName(r.priority), hoursSpent, loadPerMonth,
+			createdAt.Format("2006-01-02"), formatDate(dueDate), overdueMark(r.overdue), username, department, labels, r.id}
+		report = append(report, r)
+	}
+
+	writeTaskSheet(f, "Все задачи", headers, report)
+	if err := h.writeHoursSheet(f, from, to, "1 = 1"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	c.Header("Content-Disposition", "attachment; filename=all_tasks.xlsx")
 	f.Write(c.Writer)
 }
+
+// ExportTimesheets выгружает утверждённые табели: менеджер — своего отдела, админ — всех или ?department=.
+// Период ?from=&to= задаёт диапазон начала недели.
+func (h *ReportHandler) ExportTimesheets(c *gin.Context) {
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
+
+	scope, args := "1 = 1", []interface{}{}
+	if c.GetString("userRole") != "admin" {
+		scope, args = "u.department = ?", []interface{}{c.GetString("userDepartment")}
+	} else if department := c.Query("department"); department != "" {
+		scope, args = "u.department = ?", []interface{}{department}
+	}
+	args = append([]interface{}{from, to}, args...)
+
+	rows, err := h.db.Query(timesheetSelectSQL+`
+        WHERE t.status = 'approved' AND t.week_start BETWEEN ? AND ? AND `+scope+`
+        ORDER BY u.department, u.username, t.week_start
+    `, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var timesheets []models.Timesheet
+	for rows.Next() {
+		ts, err := scanTimesheet(rows)
+		if err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		timesheets = append(timesheets, ts)
+	}
+	rows.Close()
+
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "Табели")
+
+	var summary, details [][]interface{}
+	for _, ts := range timesheets {
+		reviewedAt := ""
+		if ts.ReviewedAt != nil {
+			reviewedAt = ts.ReviewedAt.Format(dateLayout)
+		}
+		summary = append(summary, []interface{}{ts.Username, ts.Department, ts.Week, ts.WeekStart, ts.WeekEnd, ts.Hours, ts.ReviewerName, reviewedAt})
+
+		entries, err := h.db.Query(timeEntrySelectSQL+`
+            WHERE te.user_id = ? AND te.entry_date BETWEEN ? AND ?
+            ORDER BY te.entry_date, te.id
+        `, ts.UserID, ts.WeekStart, ts.WeekEnd)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for entries.Next() {
+			entry, err := scanTimeEntry(entries)
+			if err != nil {
+				entries.Close()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			details = append(details, []interface{}{ts.Username, ts.Week, entry.Date, entry.TaskTitle,
+				float64(entry.Minutes) / 60, entry.Note})
+		}
+		entries.Close()
+	}
+
+	writeSheetRows(f, "Табели", []string{"Сотрудник", "Отдел", "Неделя", "Начало", "Конец", "Часов", "Утвердил", "Дата утверждения"}, summary)
+	f.NewSheet("Записи")
+	writeSheetRows(f, "Записи", []string{"Сотрудник", "Неделя", "Дата", "Задача", "Часов", "Комментарий"}, details)
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename=timesheets.xlsx")
+	f.Write(c.Writer)
+}
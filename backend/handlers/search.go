@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-management-backend/database"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type taskSearchResult struct {
+	models.Task
+	Rank               float64 `json:"rank"`
+	TitleHighlight     string  `json:"title_highlight"`
+	DescriptionSnippet string  `json:"description_snippet"`
+}
+
+// ftsQuery превращает пользовательский ввод в безопасный запрос FTS5:
+// каждое слово берётся в кавычки и ищется по префиксу, слова объединяются через AND
+func ftsQuery(q string) string {
+	words := strings.Fields(q)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// FTS5 обрамляет совпадения этими символами; в разметку <mark> они превращаются
+// уже после экранирования текста задачи
+const (
+	highlightOpen  = "\x02"
+	highlightClose = "\x03"
+)
+
+// markHighlights экранирует HTML в тексте задачи и заменяет маркеры совпадений на <mark>
+func markHighlights(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightOpen, "<mark>")
+	return strings.ReplaceAll(s, highlightClose, "</mark>")
+}
+
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пустой поисковый запрос"})
+		return
+	}
+
+	limit := 20
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit должен быть от 1 до 100"})
+			return
+		}
+		limit = n
+	}
+
+	// Видимость та же, что и в GetTasks
+	scope, args := taskReadScope(c)
+
+	var query string
+	if database.FullTextSearch {
+		query = `
+            SELECT ` + taskColumnsSQL + `,
+                   bm25(tasks_fts),
+                   highlight(tasks_fts, 0, char(2), char(3)),
+                   COALESCE(snippet(tasks_fts, 1, char(2), char(3), '…', 16), '')
+            FROM tasks_fts
+            JOIN tasks t ON t.id = tasks_fts.rowid ` + taskJoinsSQL + `
+            WHERE tasks_fts MATCH ? AND ` + scope + `
+            ORDER BY bm25(tasks_fts)
+            LIMIT ?`
+		args = append([]interface{}{ftsQuery(q)}, args...)
+	} else {
+		// Запасной вариант без FTS5: без ранжирования и подсветки
+		query = `
+            SELECT ` + taskColumnsSQL + `, 0, t.title, COALESCE(t.description, '')
+            FROM tasks t ` + taskJoinsSQL + `
+            WHERE (t.title LIKE ? OR t.description LIKE ?) AND ` + scope + `
+            ORDER BY t.updated_at DESC
+            LIMIT ?`
+		args = append([]interface{}{"%" + q + "%", "%" + q + "%"}, args...)
+	}
+	args = append(args, limit)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	results := []taskSearchResult{}
+	for rows.Next() {
+		var r taskSearchResult
+		task, err := scanTask(rows, &r.Rank, &r.TitleHighlight, &r.DescriptionSnippet)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		r.TitleHighlight = markHighlights(r.TitleHighlight)
+		r.DescriptionSnippet = markHighlights(r.DescriptionSnippet)
+		r.Task = task
+		results = append(results, r)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
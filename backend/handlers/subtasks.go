@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type subtaskRef struct {
+	id     int
+	userID int
+}
+
+// subtaskTree возвращает все подзадачи задачи на любой глубине (кроме лежащих в корзине)
+func subtaskTree(q querier, taskID int) ([]subtaskRef, error) {
+	rows, err := q.Query(`
+        WITH RECURSIVE tree(id) AS (
+            SELECT id FROM tasks WHERE parent_id = ? AND deleted_at IS NULL
+            UNION
+            SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at IS NULL
+        )
+        SELECT t.id, t.user_id FROM tasks t JOIN tree ON t.id = tree.id
+    `, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []subtaskRef
+	for rows.Next() {
+		var r subtaskRef
+		if err := rows.Scan(&r.id, &r.userID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	return refs, rows.Err()
+}
+
+// subtaskIDs возвращает прямые подзадачи задачи
+func subtaskIDs(q querier, taskID int) ([]int, error) {
+	rows, err := q.Query("SELECT id FROM tasks WHERE parent_id = ? AND deleted_at IS NULL ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// weightedProgress считает средний прогресс по строкам (progress, hours_per_week, load_per_month).
+// Вес строки — hours_per_week, если часы заданы хоть у одной, иначе load_per_month,
+// иначе все строки равноценны. Возвращает прогресс и число строк.
+// Вес берётся из плановых часов, а не из учтённых: у ещё не начатой задачи учтённых часов нет,
+// и с нулевым весом она не тянула бы прогресс родителя вниз.
+func weightedProgress(rows *sql.Rows) (int, int, error) {
+	var progress, hours, load []float64
+	var hoursSum, loadSum float64
+	for rows.Next() {
+		var p, h, l float64
+		if err := rows.Scan(&p, &h, &l); err != nil {
+			return 0, 0, err
+		}
+		progress = append(progress, p)
+		hours = append(hours, h)
+		load = append(load, l)
+		hoursSum += h
+		loadSum += l
+	}
+	if err := rows.Err(); err != nil || len(progress) == 0 {
+		return 0, 0, err
+	}
+
+	var total, weightSum float64
+	for i, p := range progress {
+		weight := 1.0
+		switch {
+		case hoursSum > 0:
+			weight = hours[i]
+		case loadSum > 0:
+			weight = load[i]
+		}
+		total += p * weight
+		weightSum += weight
+	}
+	return int(math.Round(total / weightSum)), len(progress), nil
+}
+
+// rollupProgress пересчитывает прогресс задачи из её подзадач и поднимается вверх по родителям.
+// Веса подзадач — как в weightedProgress. Задача без подзадач, но с чек-листом получает
+// долю отмеченных пунктов; задача без подзадач и чек-листа свой прогресс сохраняет.
+// Изменения прогресса пишутся в историю как действие rollup от имени actorID.
+func rollupProgress(q querier, taskID, actorID int) error {
+	visited := map[int]bool{}
+	current := sql.NullInt64{Int64: int64(taskID), Valid: true}
+
+	for current.Valid && !visited[int(current.Int64)] {
+		id := int(current.Int64)
+		visited[id] = true
+
+		rows, err := q.Query("SELECT progress, hours_per_week, load_per_month FROM tasks WHERE parent_id = ? AND deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+
+		rolled, count, err := weightedProgress(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			if rolled, count, err = checklistProgress(q, id); err != nil {
+				return err
+			}
+		}
+
+		if count > 0 {
+			before, err := taskSnapshot(q, id)
+			if err != nil {
+				return err
+			}
+			_, err = q.Exec(
+				"UPDATE tasks SET progress = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND progress != ?",
+				rolled, id, rolled,
+			)
+			if err != nil {
+				return err
+			}
+			after, err := taskSnapshot(q, id)
+			if err != nil {
+				return err
+			}
+			if err := recordHistory(q, id, actorID, "rollup", before, after); err != nil {
+				return err
+			}
+		}
+
+		if err := q.QueryRow("SELECT parent_id FROM tasks WHERE id = ?", id).Scan(&current); err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) GetSubtasks(c *gin.Context) {
+	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	// Прямые подзадачи; видимость родителя даёт право видеть его ветку.
+	// Подписка на родителя ветку не открывает: тогда видны только подзадачи, доступные по taskReadScope.
+	scope, args := "1 = 1", []interface{}{}
+	visible, err := taskVisible(h.db, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !visible {
+		readable, err := taskReadable(h.db, c, taskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !readable {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Задача не найдена"})
+			return
+		}
+		scope, args = taskReadScope(c)
+	}
+
+	rows, err := h.db.Query(taskSelectSQL+`
+        WHERE t.parent_id = ? AND t.deleted_at IS NULL AND `+scope+`
+        ORDER BY t.created_at, t.id
+    `, append([]interface{}{taskID}, args...)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+func (h *TaskHandler) CreateSubtask(c *gin.Context) {
+	parentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var task models.Task
+	if err := c.ShouldBindJSON(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	task.ParentID = &parentID
+
+	h.createTask(c, task)
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTaskPageSize = 50
+	maxTaskPageSize     = 500
+)
+
+type sortKey struct {
+	expr string
+	text bool // значение хранится строкой (даты), при чтении курсора не приводить к time.Time
+	desc bool
+}
+
+// Поля, по которым разрешена сортировка GET /api/tasks
+var taskSortFields = map[string]sortKey{
+	"created_at":     {expr: "t.created_at", text: true},
+	"updated_at":     {expr: "t.updated_at", text: true},
+	"due_date":       {expr: "COALESCE(t.due_date, '9999-12-31')", text: true},
+	"title":          {expr: "t.title", text: true},
+	"progress":       {expr: "t.progress"},
+	"hours_per_week": {expr: "t.hours_per_week"},
+	"load_per_month": {expr: "t.load_per_month"},
+}
+
+// taskCursor — позиция последней отданной строки для keyset-пагинации
+type taskCursor struct {
+	Sort   string        `json:"s"`
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v"`
+}
+
+func encodeTaskCursor(cursor taskCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(s string) (taskCursor, error) {
+	var cursor taskCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, errors.New("Некорректный cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errors.New("Некорректный cursor")
+	}
+	return cursor, nil
+}
+
+// parseTaskFilters переводит параметры запроса GET /api/tasks в условия WHERE.
+// Видимость по ролям сюда не входит — её добавляет taskScope.
+func parseTaskFilters(c *gin.Context) ([]string, []interface{}, error) {
+	var where []string
+	var args []interface{}
+
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, nil, errors.New("user_id должен быть числом")
+		}
+		where = append(where, "t.user_id = ?")
+		args = append(args, id)
+	}
+	if v := c.Query("department"); v != "" {
+		where = append(where, "u.department = ?")
+		args = append(args, v)
+	}
+	// parent_id=none — только задачи верхнего уровня
+	if v := c.Query("parent_id"); v != "" {
+		if v == "none" {
+			where = append(where, "t.parent_id IS NULL")
+		} else {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, nil, errors.New("parent_id должен быть числом или none")
+			}
+			where = append(where, "t.parent_id = ?")
+			args = append(args, id)
+		}
+	}
+	// project_id=none — задачи вне проектов
+	if v := c.Query("project_id"); v != "" {
+		if v == "none" {
+			where = append(where, "t.project_id IS NULL")
+		} else {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, nil, errors.New("project_id должен быть числом или none")
+			}
+			where = append(where, "t.project_id = ?")
+			args = append(args, id)
+		}
+	}
+	if v := c.Query("status"); v != "" {
+		where = append(where, "t.status = ?")
+		args = append(args, v)
+	}
+	if v := c.Query("priority"); v != "" {
+		if !validPriority(v) {
+			return nil, nil, errors.New("priority может быть low, normal, high или critical")
+		}
+		where = append(where, "t.priority = ?")
+		args = append(args, v)
+	}
+
+	for _, p := range []struct{ param, cond string }{
+		{"progress_min", "t.progress >= ?"},
+		{"progress_max", "t.progress <= ?"},
+	} {
+		if v := c.Query(p.param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, nil, errors.New(p.param + " должен быть числом")
+			}
+			where = append(where, p.cond)
+			args = append(args, n)
+		}
+	}
+
+	// Диапазоны дат включительные: created_to=2024-05-31 захватывает весь день
+	for _, p := range []struct{ param, cond string }{
+		{"created_from", "t.created_at >= ?"},
+		{"created_to", "t.created_at < date(?, '+1 day')"},
+		{"updated_from", "t.updated_at >= ?"},
+		{"updated_to", "t.updated_at < date(?, '+1 day')"},
+		{"due_before", "t.due_date IS NOT NULL AND t.due_date < ?"},
+	} {
+		if v := c.Query(p.param); v != "" {
+			if _, err := time.Parse(dateLayout, v); err != nil {
+				return nil, nil, errors.New(p.param + " должен быть в формате ГГГГ-ММ-ДД")
+			}
+			where = append(where, p.cond)
+			args = append(args, v)
+		}
+	}
+
+	// label можно повторять: задача должна нести все перечисленные метки (по ID или названию)
+	for _, v := range c.QueryArray("label") {
+		if id, err := strconv.Atoi(v); err == nil {
+			where = append(where, "EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = t.id AND tl.label_id = ?)")
+			args = append(args, id)
+		} else {
+			where = append(where, `EXISTS (SELECT 1 FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+                WHERE tl.task_id = t.id AND l.name = ? COLLATE NOCASE)`)
+			args = append(args, v)
+		}
+	}
+
+	if c.Query("overdue") == "true" {
+		where = append(where, overdueSQL)
+	}
+	if c.Query("blocked") == "true" {
+		where = append(where, isBlockedSQL)
+	}
+
+	if v := strings.TrimSpace(c.Query("q")); v != "" {
+		where = append(where, "(t.title LIKE ? OR t.description LIKE ?)")
+		args = append(args, "%"+v+"%", "%"+v+"%")
+	}
+
+	return where, args, nil
+}
+
+// parseTaskSort возвращает имя сортировки, направление и ключи сортировки
+// (последний ключ — всегда t.id, чтобы порядок был однозначным для курсора).
+// По умолчанию задачи идут по приоритету, затем по ближайшему сроку, затем новые выше.
+func parseTaskSort(c *gin.Context) (string, string, []sortKey, error) {
+	sort := c.DefaultQuery("sort", "priority")
+
+	order := strings.ToLower(c.DefaultQuery("order", "desc"))
+	if order != "asc" && order != "desc" {
+		return "", "", nil, errors.New("order может быть asc или desc")
+	}
+	desc := order == "desc"
+
+	if sort == "priority" {
+		return sort, order, []sortKey{
+			{expr: priorityRankSQL, desc: desc},
+			{expr: taskSortFields["due_date"].expr, text: true, desc: !desc},
+			{expr: "t.created_at", text: true, desc: desc},
+			{expr: "t.id", desc: desc},
+		}, nil
+	}
+
+	key, ok := taskSortFields[sort]
+	if !ok {
+		return "", "", nil, errors.New("Сортировка по полю " + sort + " не поддерживается")
+	}
+
+	key.desc = desc
+	return sort, order, []sortKey{key, {expr: "t.id", desc: key.desc}}, nil
+}
+
+func orderByClause(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.expr
+		if k.desc {
+			parts[i] += " DESC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keysetCondition строит условие "строка идёт после values" для заданного порядка:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func keysetCondition(keys []sortKey, values []interface{}) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].expr+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if k.desc {
+			op = " < ?"
+		}
+		ands = append(ands, k.expr+op)
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// cursorValues читает значения ключей сортировки для задачи taskID
+func cursorValues(q querier, keys []sortKey, taskID int) ([]interface{}, error) {
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = k.expr
+		if k.text {
+			cols[i] = "CAST(" + k.expr + " AS TEXT)"
+		}
+	}
+
+	values := make([]interface{}, len(keys))
+	dest := make([]interface{}, len(keys))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	err := q.QueryRow(`
+        SELECT `+strings.Join(cols, ", ")+`
+        FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE t.id = ?
+    `, taskID).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+	return values, nil
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"task-management-backend/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,40 +20,284 @@ func NewTaskHandler(db *sql.DB) *TaskHandler {
 	return &TaskHandler{db: db}
 }
 
-func (h *TaskHandler) GetTasks(c *gin.Context) {
-	userID := c.GetInt("userID")
-	userRole := c.GetString("userRole")
-	userDepartment := c.GetString("userDepartment")
+// querier — общее для *sql.DB и *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Колонки задачи в порядке, который ожидает scanTask (алиасы из taskJoinsSQL)
+const taskColumnsSQL = `
+    t.id, t.title, t.description, t.progress, t.hours_per_week, ` + loggedHoursSQL + `, t.load_per_month,
+    t.status, ` + statusNameSQL + `, t.priority, t.start_date, t.due_date, ` + overdueSQL + `,
+    t.parent_id, t.recurring_id, t.project_id, (SELECT COUNT(*) FROM tasks st WHERE st.parent_id = t.id AND st.deleted_at IS NULL),
+    (SELECT GROUP_CONCAT(d.blocked_by_id) FROM task_dependencies d
+     JOIN tasks b ON b.id = d.blocked_by_id
+     WHERE d.task_id = t.id AND b.deleted_at IS NULL), ` + isBlockedSQL + `, ` + taskLabelsSQL + `,
+    t.version, ` + boardPositionSQL + `, t.user_id, COALESCE(t.created_by, t.user_id),
+    COALESCE(cb.username, ''), t.created_at, t.updated_at, u.username, u.department`
+
+const taskJoinsSQL = `
+    JOIN users u ON t.user_id = u.id
+    LEFT JOIN users cb ON t.created_by = cb.id`
+
+const taskSelectSQL = `SELECT ` + taskColumnsSQL + ` FROM tasks t ` + taskJoinsSQL
+
+// scanTask читает строку с колонками taskColumnsSQL; extra — дополнительные колонки после них
+func scanTask(row rowScanner, extra ...interface{}) (models.Task, error) {
+	var task models.Task
+	var department sql.NullString
+	var startDate, dueDate sql.NullTime
+	var parentID, recurringID, projectID sql.NullInt64
+	var blockedBy sql.NullString
+	var labels string
+
+	dest := []interface{}{
+		&task.ID, &task.Title, &task.Description, &task.Progress,
+		&task.HoursPerWeek, &task.LoggedHours, &task.LoadPerMonth, &task.Status, &task.StatusName, &task.Priority,
+		&startDate, &dueDate, &task.IsOverdue,
+		&parentID, &recurringID, &projectID, &task.SubtaskCount, &blockedBy, &task.IsBlocked, &labels,
+		&task.Version, &task.Position, &task.UserID, &task.CreatedBy, &task.CreatorName,
+		&task.CreatedAt, &task.UpdatedAt, &task.Username, &department,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return task, err
+	}
+
+	if department.Valid {
+		task.Department = department.String
+	}
+	if startDate.Valid {
+		task.StartDate = startDate.Time.Format(dateLayout)
+	}
+	if dueDate.Valid {
+		task.DueDate = dueDate.Time.Format(dateLayout)
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		task.ParentID = &id
+	}
+	if recurringID.Valid {
+		id := int(recurringID.Int64)
+		task.RecurringID = &id
+	}
+	if projectID.Valid {
+		id := int(projectID.Int64)
+		task.ProjectID = &id
+	}
+	task.BlockedBy = splitIDs(blockedBy.String)
+	if err := json.Unmarshal([]byte(labels), &task.Labels); err != nil {
+		return task, err
+	}
+
+	return task, nil
+}
+
+const dateLayout = "2006-01-02"
+
+// Приоритеты задач по возрастанию важности
+var taskPriorities = []string{"low", "normal", "high", "critical"}
+
+const defaultPriority = "normal"
+
+// Вес приоритета задачи t для сортировки: чем важнее, тем больше
+const priorityRankSQL = `(CASE t.priority WHEN 'critical' THEN 3 WHEN 'high' THEN 2 WHEN 'low' THEN 0 ELSE 1 END)`
+
+func validPriority(priority string) bool {
+	for _, p := range taskPriorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// nullableDate превращает пустую дату в NULL для записи в БД
+func nullableDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+	return date
+}
 
-	var rows *sql.Rows
+// validateTask проверяет поля задачи и возвращает текст ошибки, либо пустую строку
+func validateTask(task models.Task) string {
+	if task.Progress < 0 || task.Progress > 100 {
+		return "Прогресс может быть в промежутке от 0 до 100"
+	}
+	if task.LoadPerMonth < 0 || task.LoadPerMonth > 100 {
+		return "Нагрузка может быть в промежутке от 0 до 100"
+	}
+	if task.HoursPerWeek < 0 {
+		return "Часы не могут быть отрицательными"
+	}
+	if task.Priority != "" && !validPriority(task.Priority) {
+		return "Приоритет может быть low, normal, high или critical"
+	}
+
+	var start, due time.Time
 	var err error
+	if task.StartDate != "" {
+		if start, err = time.Parse(dateLayout, task.StartDate); err != nil {
+			return "Дата начала должна быть в формате ГГГГ-ММ-ДД"
+		}
+	}
+	if task.DueDate != "" {
+		if due, err = time.Parse(dateLayout, task.DueDate); err != nil {
+			return "Срок выполнения должен быть в формате ГГГГ-ММ-ДД"
+		}
+	}
+	if !start.IsZero() && !due.IsZero() && due.Before(start) {
+		return "Срок выполнения не может быть раньше даты начала"
+	}
+
+	return ""
+}
+
+// taskScope возвращает условие видимости задач для текущего пользователя:
+// админ видит всё, менеджер — свой отдел, пользователь — свои задачи.
+// Задачи в корзине не видны никому. На нём строятся все проверки перед изменением задачи.
+func taskScope(c *gin.Context) (string, []interface{}) {
+	scope, args := roleScope(c)
+	return "t.deleted_at IS NULL AND " + scope, args
+}
+
+// taskReadScope — taskScope плюс задачи, на которые пользователя подписали (watchGrantSQL).
+// Только для чтения: подписка не даёт права что-либо менять в задаче.
+func taskReadScope(c *gin.Context) (string, []interface{}) {
+	scope, args := taskScope(c)
+	if c.GetString("userRole") == "admin" {
+		return scope, args
+	}
+	return "t.deleted_at IS NULL AND (" + scope + " OR " + watchGrantSQL + ")", append(args, c.GetInt("userID"))
+}
+
+// roleScope — ограничение видимости только по роли, без учёта корзины
+func roleScope(c *gin.Context) (string, []interface{}) {
+	switch c.GetString("userRole") {
+	case "admin":
+		return "1 = 1", nil
+	case "manager":
+		return "u.department = ?", []interface{}{c.GetString("userDepartment")}
+	default:
+		return "t.user_id = ?", []interface{}{c.GetInt("userID")}
+	}
+}
+
+// taskVisible — видна ли задача текущему пользователю по правилам taskScope
+func taskVisible(q querier, c *gin.Context, taskID int) (bool, error) {
+	scope, args := taskScope(c)
+	return taskInScope(q, taskID, scope, args)
+}
+
+// taskReadable — может ли пользователь читать задачу, в том числе по подписке
+func taskReadable(q querier, c *gin.Context, taskID int) (bool, error) {
+	scope, args := taskReadScope(c)
+	return taskInScope(q, taskID, scope, args)
+}
 
-	switch userRole {
+func taskInScope(q querier, taskID int, scope string, args []interface{}) (bool, error) {
+	var exists int
+	err := q.QueryRow(`
+        SELECT 1 FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE t.id = ? AND `+scope, append([]interface{}{taskID}, args...)...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkAssignee проверяет, может ли текущий пользователь назначить задачу на assigneeID:
+// админ — на любого, менеджер — на сотрудников своего отдела, пользователь — только на себя.
+// Возвращает HTTP-статус и текст ошибки, либо 0, если назначение разрешено.
+func checkAssignee(q querier, c *gin.Context, assigneeID int) (int, string) {
+	var department sql.NullString
+	err := q.QueryRow("SELECT department FROM users WHERE id = ?", assigneeID).Scan(&department)
+	if err == sql.ErrNoRows {
+		return http.StatusBadRequest, "Пользователь не найден"
+	} else if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+
+	switch c.GetString("userRole") {
 	case "admin":
-		rows, err = h.db.Query(`
-            SELECT t.*, u.username, u.department 
-            FROM tasks t 
-            JOIN users u ON t.user_id = u.id 
-            ORDER BY t.created_at DESC
-        `)
+		return 0, ""
 	case "manager":
-		rows, err = h.db.Query(`
-            SELECT t.*, u.username, u.department 
-            FROM tasks t 
-            JOIN users u ON t.user_id = u.id 
-            WHERE u.department = ? 
-            ORDER BY t.created_at DESC
-        `, userDepartment)
+		if department.String != c.GetString("userDepartment") {
+			return http.StatusForbidden, "Менеджер может назначать задачи только сотрудникам своего отдела"
+		}
+		return 0, ""
 	default:
-		rows, err = h.db.Query(`
-            SELECT t.*, u.username, u.department 
-            FROM tasks t 
-            JOIN users u ON t.user_id = u.id 
-            WHERE t.user_id = ? 
-            ORDER BY t.created_at DESC
-        `, userID)
+		if assigneeID != c.GetInt("userID") {
+			return http.StatusForbidden, "В доступе отказано"
+		}
+		return 0, ""
+	}
+}
+
+func (h *TaskHandler) GetTasks(c *gin.Context) {
+	scope, args := taskReadScope(c)
+
+	filters, filterArgs, err := parseTaskFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	where := append([]string{scope}, filters...)
+	args = append(args, filterArgs...)
+
+	sort, order, keys, err := parseTaskSort(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Без limit и cursor отдаём весь список массивом, как раньше
+	_, hasLimit := c.GetQuery("limit")
+	cursorParam := c.Query("cursor")
+	paginated := hasLimit || cursorParam != ""
+
+	limit := defaultTaskPageSize
+	if hasLimit {
+		limit, err = strconv.Atoi(c.Query("limit"))
+		if err != nil || limit < 1 || limit > maxTaskPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit должен быть от 1 до " + strconv.Itoa(maxTaskPageSize)})
+			return
+		}
+	}
+
+	if cursorParam != "" {
+		cursor, err := decodeTaskCursor(cursorParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cursor.Sort != sort || cursor.Order != order || len(cursor.Values) != len(keys) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor не соответствует параметрам сортировки"})
+			return
+		}
+		cond, condArgs := keysetCondition(keys, cursor.Values)
+		where = append(where, cond)
+		args = append(args, condArgs...)
 	}
 
+	query := taskSelectSQL + `
+        WHERE ` + strings.Join(where, " AND ") + `
+        ORDER BY ` + orderByClause(keys)
+	if paginated {
+		// Берём на одну строку больше, чтобы понять, есть ли следующая страница
+		query += " LIMIT ?"
+		args = append(args, limit+1)
+	}
+
+	rows, err := h.db.Query(query, args...)
+
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -59,122 +306,659 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 
 	tasks := []models.Task{}
 	for rows.Next() {
-		var task models.Task
-		var department sql.NullString
-
-		err := rows.Scan(
-			&task.ID, &task.Title, &task.Description, &task.Progress,
-			&task.HoursPerWeek, &task.LoadPerMonth, &task.UserID,
-			&task.CreatedAt, &task.UpdatedAt, &task.Username, &department,
-		)
+		task, err := scanTask(rows)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		if department.Valid {
-			task.Department = department.String
-		}
-
 		tasks = append(tasks, task)
 	}
+	rows.Close()
+
+	if !paginated {
+		c.JSON(http.StatusOK, tasks)
+		return
+	}
+
+	var nextCursor *string
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		values, err := cursorValues(h.db, keys, tasks[limit-1].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		next := encodeTaskCursor(taskCursor{Sort: sort, Order: order, Values: values})
+		nextCursor = &next
+	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":       tasks,
+		"next_cursor": nextCursor,
+	})
 }
 
 func (h *TaskHandler) CreateTask(c *gin.Context) {
-	userID := c.GetInt("userID")
-
 	var task models.Task
 	if err := c.ShouldBindJSON(&task); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	result, err := h.db.Exec(`
-        INSERT INTO tasks (title, description, progress, hours_per_week, load_per_month, user_id)
-        VALUES (?, ?, ?, ?, ?, ?)
-    `, task.Title, task.Description, task.Progress, task.HoursPerWeek, task.LoadPerMonth, userID)
+	h.createTask(c, task)
+}
+
+// createTask — общая часть CreateTask и CreateSubtask
+func (h *TaskHandler) createTask(c *gin.Context, task models.Task) {
+	userID := c.GetInt("userID")
+
+	if msg := validateTask(task); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if task.Priority == "" {
+		task.Priority = defaultPriority
+	}
+
+	// Исполнитель по умолчанию — сам автор
+	assigneeID := userID
+	if task.AssigneeID != 0 && task.AssigneeID != userID {
+		if status, msg := checkAssignee(h.db, c, task.AssigneeID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		assigneeID = task.AssigneeID
+	}
 
+	// Статус по умолчанию — начальное состояние workflow отдела исполнителя
+	wf, err := taskWorkflow(h.db, assigneeID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if task.Status == "" {
+		task.Status = workflowInitialState(wf)
+	} else if !workflowHasState(wf, task.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный статус: " + task.Status})
+		return
+	}
+
+	// Подзадачу можно создать только под видимой автору задачей
+	if task.ParentID != nil {
+		visible, err := taskVisible(h.db, c, *task.ParentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !visible {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Родительская задача не найдена"})
+			return
+		}
+	}
 
-	id, _ := result.LastInsertId()
-	task.ID = int(id)
+	// Включить задачу можно только в проект, где автор участник или владелец
+	if task.ProjectID != nil {
+		visible, err := projectVisible(h.db, c, *task.ProjectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !visible {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	ids, overloads, status, body := createTasks(tx, assigneeID, userID, task)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	task.ID = ids[0]
+	task.UserID = assigneeID
+	task.AssigneeID = 0
+	task.CreatedBy = userID
+	task.Version = 1
+	task.CapacityWarnings = overloads
+	c.Header("ETag", taskETag(task.Version))
 	c.JSON(http.StatusCreated, task)
 }
 
+// createTasks — общая часть createTask, шаблонов и импорта: создаёт уже проверенные задачи сотрудника ownerID
+// от имени userID, пишет историю, пересчитывает прогресс родителей и добавляет сотрудника в проекты задач.
+// Загрузка сравнивается до и после создания всех задач. Возвращает ID задач и месяцы, в которых
+// они перегрузили сотрудника, либо HTTP-статус и тело ошибки.
+func createTasks(q querier, ownerID, userID int, tasks ...models.Task) ([]int, []models.UserCapacity, int, gin.H) {
+	capacity, err := newCapacityGuard(q, ownerID, tasks...)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		result, err := q.Exec(`
+            INSERT INTO tasks (title, description, progress, hours_per_week, load_per_month, status, priority,
+                               start_date, due_date, parent_id, project_id, user_id, created_by)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        `, task.Title, task.Description, task.Progress, task.HoursPerWeek, task.LoadPerMonth, task.Status, task.Priority,
+			nullableDate(task.StartDate), nullableDate(task.DueDate), task.ParentID, task.ProjectID, ownerID, userID)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+
+		id, _ := result.LastInsertId()
+		ids[i] = int(id)
+		after, err := taskSnapshot(q, ids[i])
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		if err := recordHistory(q, ids[i], userID, "create", nil, after); err != nil {
+			return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+
+		if task.ParentID != nil {
+			if err := rollupProgress(q, *task.ParentID, userID); err != nil {
+				return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+		}
+		if task.ProjectID != nil {
+			if err := addProjectMember(q, *task.ProjectID, ownerID); err != nil {
+				return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+		}
+	}
+
+	overloads, err := capacity.check(q)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if status, body := capacityConflict(overloads); status != 0 {
+		return nil, nil, status, body
+	}
+
+	return ids, overloads, 0, nil
+}
+
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	var task models.Task
+	if err := c.ShouldBindJSON(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// If-Match защищает от перезаписи чужих изменений
+	if status, body := checkIfMatch(tx, c, taskID); status != 0 {
+		c.JSON(status, body)
+		return
+	}
+	overloads, status, body := updateTask(tx, c, taskID, task)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+	version, err := taskVersion(tx, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", taskETag(version))
+	response := gin.H{"message": "Задача успешно обновлена", "version": version}
+	if len(overloads) > 0 {
+		response["capacity_warnings"] = overloads
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTask возвращает одну задачу с теми же ограничениями по ролям, что и GetTasks
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	scope, args := taskReadScope(c)
+	task, err := scanTask(h.db.QueryRow(taskSelectSQL+" WHERE t.id = ? AND "+scope, append([]interface{}{taskID}, args...)...))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Задача не найдена"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", taskETag(task.Version))
+	c.JSON(http.StatusOK, task)
+}
+
+// PatchTask меняет только переданные поля задачи; остальные остаются как есть
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	var patch taskPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if patch.empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите изменяемые поля"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if status, body := checkIfMatch(tx, c, taskID); status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	// Отсутствующую задачу и задачу без доступа различает updateTask
+	task, err := loadTask(tx, taskID)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	patch.apply(&task)
+	overloads, status, body := updateTask(tx, c, taskID, task)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	updated, err := loadTask(tx, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated.CapacityWarnings = overloads
+	c.Header("ETag", taskETag(updated.Version))
+	c.JSON(http.StatusOK, updated)
+}
+
+// updateTask — общая часть UpdateTask и массовых операций: проверяет права, workflow и блокировки,
+// сохраняет задачу и пишет историю. Возвращает месяцы, где изменение перегрузило владельца,
+// либо HTTP-статус и тело ошибки.
+func updateTask(q querier, c *gin.Context, taskID int, task models.Task) ([]models.UserCapacity, int, gin.H) {
 	userID := c.GetInt("userID")
 	userRole := c.GetString("userRole")
 
 	// Проверка прав доступа
 	if userRole == "user" {
 		var taskUserID int
-		err := h.db.QueryRow("SELECT user_id FROM tasks WHERE id = ?", taskID).Scan(&taskUserID)
+		err := q.QueryRow("SELECT user_id FROM tasks WHERE id = ? AND deleted_at IS NULL", taskID).Scan(&taskUserID)
 		if err != nil || taskUserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
-			return
+			return nil, http.StatusForbidden, gin.H{"error": "В доступе отказано"}
 		}
 	}
 
-	var task models.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	// Валидация данных
+	if msg := validateTask(task); msg != "" {
+		return nil, http.StatusBadRequest, gin.H{"error": msg}
 	}
 
-	// Валидация данных
-	if task.Progress < 0 || task.Progress > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Прогресс может быть в промежутке от 0 до 100"})
-		return
+	// Проверка перехода статуса по workflow отдела владельца
+	var currentStatus, currentPriority string
+	var ownerID, currentProgress int
+	err := q.QueryRow("SELECT status, priority, user_id, progress FROM tasks WHERE id = ? AND deleted_at IS NULL", taskID).
+		Scan(&currentStatus, &currentPriority, &ownerID, &currentProgress)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, gin.H{"error": "Задача не найдена"}
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
 	}
-	if task.LoadPerMonth < 0 || task.LoadPerMonth > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Нагрузка может быть в промежутке от 0 до 100"})
-		return
+
+	// Клиенты, не знающие о приоритете, его не сбрасывают
+	if task.Priority == "" {
+		task.Priority = currentPriority
 	}
-	if task.HoursPerWeek < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Часы не могут быть отрицательными"})
-		return
+
+	if task.Status == "" {
+		task.Status = currentStatus
+	} else if task.Status != currentStatus {
+		wf, err := taskWorkflow(q, ownerID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		if !workflowHasState(wf, task.Status) {
+			return nil, http.StatusBadRequest, gin.H{"error": "Неизвестный статус: " + task.Status}
+		}
+		if !workflowAllows(wf, currentStatus, task.Status) {
+			return nil, http.StatusBadRequest, gin.H{"error": "Недопустимый переход статуса: " + currentStatus + " → " + task.Status}
+		}
+	}
+
+	// Пока блокирующие задачи не завершены, прогресс не растёт (кроме явного override_blockers=true)
+	if task.Progress > currentProgress && c.Query("override_blockers") != "true" {
+		blockers, err := openBlockers(q, taskID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		if len(blockers) > 0 {
+			return nil, http.StatusConflict, gin.H{
+				"error":      "Задача заблокирована незавершёнными задачами",
+				"blocked_by": blockers,
+			}
+		}
 	}
 
-	_, err := h.db.Exec(`
+	before, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	capacity, err := newCapacityGuard(q, ownerID, task)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	_, err = q.Exec(`
         UPDATE tasks 
-        SET title = ?, description = ?, progress = ?, hours_per_week = ?, load_per_month = ?, updated_at = CURRENT_TIMESTAMP
+        SET title = ?, description = ?, progress = ?, hours_per_week = ?, load_per_month = ?, status = ?,
+            priority = ?, start_date = ?, due_date = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
         WHERE id = ?
-    `, task.Title, task.Description, task.Progress, task.HoursPerWeek, task.LoadPerMonth, taskID)
+    `, task.Title, task.Description, task.Progress, task.HoursPerWeek, task.LoadPerMonth, task.Status,
+		task.Priority, nullableDate(task.StartDate), nullableDate(task.DueDate), taskID)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Задача успешно обновлена"})
+	after, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if err := recordHistory(q, taskID, userID, "update", before, after); err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	// Прогресс задачи с подзадачами и её родителей вычисляется из подзадач
+	if err := rollupProgress(q, taskID, userID); err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	overloads, err := capacity.check(q)
+	if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if status, body := capacityConflict(overloads); status != 0 {
+		return nil, status, body
+	}
+
+	return overloads, 0, nil
 }
 
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	// Подзадачи по умолчанию поднимаются на уровень удаляемой задачи,
+	// с ?children=cascade отправляются в корзину вместе с ней
+	mode := c.DefaultQuery("children", "reparent")
+	if mode != "reparent" && mode != "cascade" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "children может быть reparent или cascade"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if status, body := checkIfMatch(tx, c, taskID); status != 0 {
+		c.JSON(status, body)
+		return
+	}
+	deleted, status, body := deleteTask(tx, c, taskID, mode)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Задача перемещена в корзину", "deleted": deleted})
+}
+
+// deleteTask — общая часть DeleteTask и массовых операций: переносит задачу в корзину
+// и возвращает ID всех перенесённых задач, либо HTTP-статус и тело ошибки
+func deleteTask(q querier, c *gin.Context, taskID int, mode string) ([]int, int, gin.H) {
 	userID := c.GetInt("userID")
 	userRole := c.GetString("userRole")
 
 	// Проверка прав доступа
 	if userRole == "user" {
 		var taskUserID int
-		err := h.db.QueryRow("SELECT user_id FROM tasks WHERE id = ?", taskID).Scan(&taskUserID)
+		err := q.QueryRow("SELECT user_id FROM tasks WHERE id = ? AND deleted_at IS NULL", taskID).Scan(&taskUserID)
 		if err != nil || taskUserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
-			return
+			return nil, http.StatusForbidden, gin.H{"error": "В доступе отказано"}
+		}
+	}
+
+	var parentID sql.NullInt64
+	err := q.QueryRow("SELECT parent_id FROM tasks WHERE id = ? AND deleted_at IS NULL", taskID).Scan(&parentID)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, gin.H{"error": "Задача не найдена"}
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	deleted := []int{taskID}
+	if mode == "cascade" {
+		descendants, err := subtaskTree(q, taskID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		// Пользователь может удалить ветку, только если все подзадачи — его
+		if userRole == "user" {
+			for _, d := range descendants {
+				if d.userID != userID {
+					return nil, http.StatusForbidden, gin.H{"error": "Среди подзадач есть задачи других сотрудников"}
+				}
+			}
+		}
+		for _, d := range descendants {
+			deleted = append(deleted, d.id)
+		}
+	} else {
+		children, err := subtaskIDs(q, taskID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
 		}
+		for _, childID := range children {
+			before, err := taskSnapshot(q, childID)
+			if err != nil {
+				return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+			if _, err := q.Exec("UPDATE tasks SET parent_id = ?, version = version + 1 WHERE id = ?", parentID, childID); err != nil {
+				return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+			after, err := taskSnapshot(q, childID)
+			if err != nil {
+				return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+			if err := recordHistory(q, childID, userID, "update", before, after); err != nil {
+				return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+			}
+		}
+	}
+
+	// Задачи не удаляются, а попадают в корзину; вся ветка получает одну метку времени,
+	// чтобы при восстановлении вернуться вместе
+	deletedAt := time.Now().UTC().Format(deletedAtLayout)
+	for _, id := range deleted {
+		before, err := taskSnapshot(q, id)
+		if err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		if _, err := q.Exec("UPDATE tasks SET deleted_at = ?, version = version + 1 WHERE id = ?", deletedAt, id); err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+		if err := recordHistory(q, id, userID, "delete", before, nil); err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+	}
+
+	if parentID.Valid {
+		if err := rollupProgress(q, int(parentID.Int64), userID); err != nil {
+			return nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+		}
+	}
+
+	return deleted, 0, nil
+}
+
+func (h *TaskHandler) ReassignTask(c *gin.Context) {
+	taskID, _ := strconv.Atoi(c.Param("id"))
+
+	var request struct {
+		AssigneeID int `json:"assignee_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	_, err := h.db.Exec("DELETE FROM tasks WHERE id = ?", taskID)
+	tx, err := h.db.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer tx.Rollback()
+
+	newStatus, overloads, status, body := reassignTask(tx, c, taskID, request.AssigneeID)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "Задача передана другому сотруднику", "user_id": request.AssigneeID, "status": newStatus}
+	if len(overloads) > 0 {
+		response["capacity_warnings"] = overloads
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// reassignTask — общая часть ReassignTask и массовых операций: передаёт задачу assigneeID
+// и возвращает её новый статус и месяцы, в которых она перегрузила нового исполнителя, либо HTTP-статус и тело ошибки
+func reassignTask(q querier, c *gin.Context, taskID, assigneeID int) (string, []models.UserCapacity, int, gin.H) {
+	var currentStatus string
+	var ownerDepartment sql.NullString
+	err := q.QueryRow(`
+        SELECT t.status, u.department FROM tasks t
+        JOIN users u ON t.user_id = u.id
+        WHERE t.id = ? AND t.deleted_at IS NULL
+    `, taskID).Scan(&currentStatus, &ownerDepartment)
+	if err == sql.ErrNoRows {
+		return "", nil, http.StatusNotFound, gin.H{"error": "Задача не найдена"}
+	} else if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	// Менеджер передаёт только задачи своего отдела
+	if c.GetString("userRole") != "admin" && ownerDepartment.String != c.GetString("userDepartment") {
+		return "", nil, http.StatusForbidden, gin.H{"error": "В доступе отказано"}
+	}
+
+	if status, msg := checkAssignee(q, c, assigneeID); status != 0 {
+		return "", nil, status, gin.H{"error": msg}
+	}
+
+	task, err := loadTask(q, taskID)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	capacity, err := newCapacityGuard(q, assigneeID, task)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	// Если в workflow нового отдела нет текущего статуса — возвращаем задачу в начальное состояние
+	wf, err := taskWorkflow(q, assigneeID)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	status := currentStatus
+	if !workflowHasState(wf, status) {
+		status = workflowInitialState(wf)
+	}
+
+	before, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	_, err = q.Exec(`
+        UPDATE tasks SET user_id = ?, status = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+    `, assigneeID, status, taskID)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	after, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if err := recordHistory(q, taskID, c.GetInt("userID"), "reassign", before, after); err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+
+	overloads, err := capacity.check(q)
+	if err != nil {
+		return "", nil, http.StatusInternalServerError, gin.H{"error": err.Error()}
+	}
+	if code, body := capacityConflict(overloads); code != 0 {
+		return "", nil, code, body
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Задача успешно удалена"})
+	return status, overloads, 0, nil
 }
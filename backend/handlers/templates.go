@@ -0,0 +1,630 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const templateSelectSQL = `
+    SELECT id, name, title, description, hours_per_week, load_per_month, priority, due_in_days, checklist,
+           department, COALESCE(created_by, 0), created_at
+    FROM task_templates`
+
+const bundleSelectSQL = `
+    SELECT id, name, description, department, COALESCE(created_by, 0), created_at
+    FROM template_bundles`
+
+type TemplateHandler struct {
+	db *sql.DB
+}
+
+func NewTemplateHandler(db *sql.DB) *TemplateHandler {
+	return &TemplateHandler{db: db}
+}
+
+func scanTemplate(row rowScanner) (models.TaskTemplate, error) {
+	var tpl models.TaskTemplate
+	var dueInDays sql.NullInt64
+	var checklist string
+	err := row.Scan(&tpl.ID, &tpl.Name, &tpl.Title, &tpl.Description, &tpl.HoursPerWeek, &tpl.LoadPerMonth, &tpl.Priority,
+		&dueInDays, &checklist, &tpl.Department, &tpl.CreatedBy, &tpl.CreatedAt)
+	if err != nil {
+		return tpl, err
+	}
+
+	if dueInDays.Valid {
+		days := int(dueInDays.Int64)
+		tpl.DueInDays = &days
+	}
+	if err := json.Unmarshal([]byte(checklist), &tpl.Checklist); err != nil {
+		return tpl, err
+	}
+	return tpl, nil
+}
+
+// departmentScope — как у меток: общие ("") и своего отдела; админ видит всё
+func departmentScope(c *gin.Context) (string, []interface{}) {
+	if c.GetString("userRole") == "admin" {
+		return "1 = 1", nil
+	}
+	return "department IN ('', ?)", []interface{}{c.GetString("userDepartment")}
+}
+
+// canManageDepartment — админ управляет любыми шаблонами и наборами, менеджер — только своего отдела
+func canManageDepartment(c *gin.Context, department string) bool {
+	return c.GetString("userRole") == "admin" || department == c.GetString("userDepartment")
+}
+
+// validateTemplate нормализует шаблон и возвращает текст ошибки, либо пустую строку
+func validateTemplate(tpl *models.TaskTemplate) string {
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	tpl.Title = strings.TrimSpace(tpl.Title)
+	if tpl.Name == "" {
+		return "Название шаблона не может быть пустым"
+	}
+	if tpl.Title == "" {
+		return "Название задачи не может быть пустым"
+	}
+	if tpl.Priority == "" {
+		tpl.Priority = defaultPriority
+	}
+	if msg := validateTask(models.Task{HoursPerWeek: tpl.HoursPerWeek, LoadPerMonth: tpl.LoadPerMonth, Priority: tpl.Priority}); msg != "" {
+		return msg
+	}
+	if tpl.DueInDays != nil && *tpl.DueInDays < 0 {
+		return "Срок не может быть отрицательным"
+	}
+	if tpl.Checklist == nil {
+		tpl.Checklist = []string{}
+	}
+	return validateChecklist(tpl.Checklist)
+}
+
+// loadTemplate находит шаблон по ID среди доступных пользователю
+func loadTemplate(q querier, c *gin.Context, id int) (models.TaskTemplate, error) {
+	scope, args := departmentScope(c)
+	return scanTemplate(q.QueryRow(templateSelectSQL+" WHERE id = ? AND "+scope, append([]interface{}{id}, args...)...))
+}
+
+// templateParam находит шаблон по :id; manage — нужно ли право на изменение
+func (h *TemplateHandler) templateParam(c *gin.Context, manage bool) (models.TaskTemplate, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return models.TaskTemplate{}, false
+	}
+
+	tpl, err := loadTemplate(h.db, c, id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Шаблон не найден"})
+		return tpl, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return tpl, false
+	}
+	if manage && !canManageDepartment(c, tpl.Department) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return tpl, false
+	}
+	return tpl, true
+}
+
+func (h *TemplateHandler) GetTemplates(c *gin.Context) {
+	scope, args := departmentScope(c)
+	rows, err := h.db.Query(templateSelectSQL+" WHERE "+scope+" ORDER BY department, name", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	templates := []models.TaskTemplate{}
+	for rows.Next() {
+		tpl, err := scanTemplate(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		templates = append(templates, tpl)
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	tpl, ok := h.templateParam(c, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var tpl models.TaskTemplate
+	if err := c.ShouldBindJSON(&tpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Менеджер создаёт шаблоны своего отдела, админ — любого или общие ("")
+	if c.GetString("userRole") != "admin" {
+		tpl.Department = c.GetString("userDepartment")
+	}
+	if msg := validateTemplate(&tpl); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	checklist, _ := json.Marshal(tpl.Checklist)
+	result, err := h.db.Exec(`
+        INSERT INTO task_templates (name, title, description, hours_per_week, load_per_month, priority, due_in_days,
+                                    checklist, department, created_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, tpl.Name, tpl.Title, tpl.Description, tpl.HoursPerWeek, tpl.LoadPerMonth, tpl.Priority, tpl.DueInDays,
+		string(checklist), tpl.Department, c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	created, err := scanTemplate(h.db.QueryRow(templateSelectSQL+" WHERE id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateTemplate меняет шаблон; непереданные поля сохраняют прежние значения.
+// Уже созданные по шаблону задачи не меняются.
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	current, ok := h.templateParam(c, true)
+	if !ok {
+		return
+	}
+
+	tpl := current
+	if err := c.ShouldBindJSON(&tpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Отдел шаблона не меняется, как и у меток
+	tpl.ID, tpl.Department, tpl.CreatedBy, tpl.CreatedAt = current.ID, current.Department, current.CreatedBy, current.CreatedAt
+	if msg := validateTemplate(&tpl); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	checklist, _ := json.Marshal(tpl.Checklist)
+	_, err := h.db.Exec(`
+        UPDATE task_templates
+        SET name = ?, title = ?, description = ?, hours_per_week = ?, load_per_month = ?, priority = ?,
+            due_in_days = ?, checklist = ?
+        WHERE id = ?
+    `, tpl.Name, tpl.Title, tpl.Description, tpl.HoursPerWeek, tpl.LoadPerMonth, tpl.Priority,
+		tpl.DueInDays, string(checklist), tpl.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// DeleteTemplate удаляет шаблон и исключает его из наборов
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	tpl, ok := h.templateParam(c, true)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM bundle_templates WHERE template_id = ?", tpl.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM task_templates WHERE id = ?", tpl.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Шаблон удалён"})
+}
+
+// bundleTemplates возвращает шаблоны набора в заданном порядке
+func bundleTemplates(q querier, bundleID int) ([]models.TaskTemplate, error) {
+	rows, err := q.Query(`
+        SELECT t.id, t.name, t.title, t.description, t.hours_per_week, t.load_per_month, t.priority, t.due_in_days,
+               t.checklist, t.department, COALESCE(t.created_by, 0), t.created_at
+        FROM bundle_templates bt
+        JOIN task_templates t ON t.id = bt.template_id
+        WHERE bt.bundle_id = ?
+        ORDER BY bt.position
+    `, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.TaskTemplate{}
+	for rows.Next() {
+		tpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tpl)
+	}
+	return templates, rows.Err()
+}
+
+func scanBundle(q querier, row rowScanner) (models.TemplateBundle, error) {
+	var b models.TemplateBundle
+	if err := row.Scan(&b.ID, &b.Name, &b.Description, &b.Department, &b.CreatedBy, &b.CreatedAt); err != nil {
+		return b, err
+	}
+
+	templates, err := bundleTemplates(q, b.ID)
+	if err != nil {
+		return b, err
+	}
+	b.Templates = templates
+	b.TemplateIDs = []int{}
+	for _, tpl := range templates {
+		b.TemplateIDs = append(b.TemplateIDs, tpl.ID)
+	}
+	return b, nil
+}
+
+// bundleParam находит набор по :id; manage — нужно ли право на изменение
+func (h *TemplateHandler) bundleParam(c *gin.Context, manage bool) (models.TemplateBundle, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bundle ID"})
+		return models.TemplateBundle{}, false
+	}
+
+	scope, args := departmentScope(c)
+	b, err := scanBundle(h.db, h.db.QueryRow(bundleSelectSQL+" WHERE id = ? AND "+scope, append([]interface{}{id}, args...)...))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Набор шаблонов не найден"})
+		return b, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return b, false
+	}
+	if manage && !canManageDepartment(c, b.Department) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+		return b, false
+	}
+	return b, true
+}
+
+// setBundleTemplates заменяет состав набора. Шаблоны должны быть доступны пользователю
+// и подходить отделу набора: общие или того же отдела. Возвращает HTTP-статус и текст ошибки, либо 0.
+func setBundleTemplates(q querier, c *gin.Context, b models.TemplateBundle, templateIDs []int) (int, string) {
+	if len(templateIDs) == 0 {
+		return http.StatusBadRequest, "Набор должен содержать хотя бы один шаблон"
+	}
+
+	seen := map[int]bool{}
+	for _, id := range templateIDs {
+		if seen[id] {
+			return http.StatusBadRequest, "Шаблон " + strconv.Itoa(id) + " указан дважды"
+		}
+		seen[id] = true
+
+		tpl, err := loadTemplate(q, c, id)
+		if err == sql.ErrNoRows {
+			return http.StatusBadRequest, "Шаблон " + strconv.Itoa(id) + " не найден"
+		} else if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if tpl.Department != "" && tpl.Department != b.Department {
+			return http.StatusBadRequest, "Шаблон " + strconv.Itoa(id) + " относится к другому отделу"
+		}
+	}
+
+	if _, err := q.Exec("DELETE FROM bundle_templates WHERE bundle_id = ?", b.ID); err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	for i, id := range templateIDs {
+		if _, err := q.Exec("INSERT INTO bundle_templates (bundle_id, template_id, position) VALUES (?, ?, ?)", b.ID, id, i+1); err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+	}
+	return 0, ""
+}
+
+func (h *TemplateHandler) GetBundles(c *gin.Context) {
+	scope, args := departmentScope(c)
+	rows, err := h.db.Query(bundleSelectSQL+" WHERE "+scope+" ORDER BY department, name", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundles := []models.TemplateBundle{}
+	for rows.Next() {
+		var b models.TemplateBundle
+		if err := rows.Scan(&b.ID, &b.Name, &b.Description, &b.Department, &b.CreatedBy, &b.CreatedAt); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		bundles = append(bundles, b)
+	}
+	rows.Close()
+
+	// В списке — только ID шаблонов, сами шаблоны отдаёт GET /bundles/:id
+	for i := range bundles {
+		templates, err := bundleTemplates(h.db, bundles[i].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		bundles[i].TemplateIDs = []int{}
+		for _, tpl := range templates {
+			bundles[i].TemplateIDs = append(bundles[i].TemplateIDs, tpl.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, bundles)
+}
+
+func (h *TemplateHandler) GetBundle(c *gin.Context) {
+	b, ok := h.bundleParam(c, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// saveBundle создаёт (b.ID == 0) или обновляет набор вместе с составом и отвечает сохранённым набором
+func (h *TemplateHandler) saveBundle(c *gin.Context, status int, b models.TemplateBundle) {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Название набора не может быть пустым"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if b.ID == 0 {
+		result, err := tx.Exec("INSERT INTO template_bundles (name, description, department, created_by) VALUES (?, ?, ?, ?)",
+			b.Name, b.Description, b.Department, c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		id, _ := result.LastInsertId()
+		b.ID = int(id)
+	} else if _, err := tx.Exec("UPDATE template_bundles SET name = ?, description = ? WHERE id = ?", b.Name, b.Description, b.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, msg := setBundleTemplates(tx, c, b, b.TemplateIDs); status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	saved, err := scanBundle(tx, tx.QueryRow(bundleSelectSQL+" WHERE id = ?", b.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, saved)
+}
+
+// CreateBundle создаёт набор {"name", "description", "template_ids": [...]}; порядок шаблонов сохраняется
+func (h *TemplateHandler) CreateBundle(c *gin.Context) {
+	var b models.TemplateBundle
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b.ID = 0
+	if c.GetString("userRole") != "admin" {
+		b.Department = c.GetString("userDepartment")
+	}
+	h.saveBundle(c, http.StatusCreated, b)
+}
+
+// UpdateBundle меняет набор; без template_ids состав остаётся прежним
+func (h *TemplateHandler) UpdateBundle(c *gin.Context) {
+	current, ok := h.bundleParam(c, true)
+	if !ok {
+		return
+	}
+
+	b := current
+	b.TemplateIDs = nil
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if b.TemplateIDs == nil {
+		b.TemplateIDs = current.TemplateIDs
+	}
+	b.ID, b.Department = current.ID, current.Department
+	h.saveBundle(c, http.StatusOK, b)
+}
+
+func (h *TemplateHandler) DeleteBundle(c *gin.Context) {
+	b, ok := h.bundleParam(c, true)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM bundle_templates WHERE bundle_id = ?", b.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM template_bundles WHERE id = ?", b.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Набор шаблонов удалён"})
+}
+
+type instantiateRequest struct {
+	UserID    int    `json:"user_id"`    // по умолчанию — сам менеджер
+	StartDate string `json:"start_date"` // YYYY-MM-DD, по умолчанию — сегодня; от неё отсчитываются сроки
+}
+
+// instantiateTemplates создаёт по задаче на каждый шаблон для сотрудника из запроса в одной транзакции
+func (h *TemplateHandler) instantiateTemplates(c *gin.Context, templates []models.TaskTemplate) {
+	userID := c.GetInt("userID")
+
+	var request instantiateRequest
+	// Тело необязательно
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	start := localDate(time.Now())
+	if request.StartDate != "" {
+		parsed, err := time.Parse(dateLayout, request.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Дата начала должна быть в формате ГГГГ-ММ-ДД"})
+			return
+		}
+		start = parsed
+	}
+
+	// Правила назначения как у обычных задач
+	assigneeID := userID
+	if request.UserID != 0 && request.UserID != userID {
+		if status, msg := checkAssignee(h.db, c, request.UserID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		assigneeID = request.UserID
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	wf, err := taskWorkflow(tx, assigneeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks := make([]models.Task, len(templates))
+	for i, tpl := range templates {
+		tasks[i] = models.Task{
+			Title:        tpl.Title,
+			Description:  tpl.Description,
+			HoursPerWeek: tpl.HoursPerWeek,
+			LoadPerMonth: tpl.LoadPerMonth,
+			Status:       workflowInitialState(wf),
+			Priority:     tpl.Priority,
+			StartDate:    start.Format(dateLayout),
+		}
+		if tpl.DueInDays != nil {
+			tasks[i].DueDate = start.AddDate(0, 0, *tpl.DueInDays).Format(dateLayout)
+		}
+	}
+
+	// Загрузку сравниваем до и после создания всех задач набора
+	ids, overloads, status, body := createTasks(tx, assigneeID, userID, tasks...)
+	if status != 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	for i, tpl := range templates {
+		if err := addChecklistItems(tx, ids[i], tpl.Checklist); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	for i := range tasks {
+		if tasks[i], err = loadTask(tx, ids[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"tasks": tasks, "capacity_warnings": overloads})
+}
+
+// InstantiateTemplate создаёт задачу по шаблону для выбранного сотрудника
+func (h *TemplateHandler) InstantiateTemplate(c *gin.Context) {
+	tpl, ok := h.templateParam(c, false)
+	if !ok {
+		return
+	}
+	h.instantiateTemplates(c, []models.TaskTemplate{tpl})
+}
+
+// InstantiateBundle создаёт все задачи набора для выбранного сотрудника одним запросом
+func (h *TemplateHandler) InstantiateBundle(c *gin.Context) {
+	b, ok := h.bundleParam(c, false)
+	if !ok {
+		return
+	}
+	if len(b.Templates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В наборе нет шаблонов"})
+		return
+	}
+	h.instantiateTemplates(c, b.Templates)
+}
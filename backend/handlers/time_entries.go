@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Часы, учтённые по задаче t за всё время
+const loggedHoursSQL = `ROUND(COALESCE((SELECT SUM(te.minutes) FROM time_entries te WHERE te.task_id = t.id), 0) / 60.0, 2)`
+
+// Часы, учтённые по задаче t за период; параметры — начало и конец периода
+const periodHoursSQL = `ROUND(COALESCE((SELECT SUM(te.minutes) FROM time_entries te WHERE te.task_id = t.id AND te.entry_date BETWEEN ? AND ?), 0) / 60.0, 2)`
+
+// Не больше суток на одну запись учёта времени, ручную или по таймеру
+const maxEntryMinutes = 24 * 60
+
+type TimeEntryHandler struct {
+	db *sql.DB
+}
+
+func NewTimeEntryHandler(db *sql.DB) *TimeEntryHandler {
+	return &TimeEntryHandler{db: db}
+}
+
+const timeEntrySelectSQL = `
+    SELECT te.id, te.task_id, t.title, te.user_id, COALESCE(u.username, ''), te.entry_date, te.minutes,
+           te.started_at, te.ended_at, te.note, te.created_at
+    FROM time_entries te
+    JOIN tasks t ON t.id = te.task_id
+    LEFT JOIN users u ON te.user_id = u.id`
+
+func scanTimeEntry(row rowScanner) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	var date time.Time
+	var startedAt, endedAt sql.NullTime
+	err := row.Scan(&entry.ID, &entry.TaskID, &entry.TaskTitle, &entry.UserID, &entry.Username, &date, &entry.Minutes,
+		&startedAt, &endedAt, &entry.Note, &entry.CreatedAt)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Date = date.Format(dateLayout)
+	if startedAt.Valid {
+		entry.StartedAt = &startedAt.Time
+	}
+	if endedAt.Valid {
+		entry.EndedAt = &endedAt.Time
+	}
+	entry.Running = startedAt.Valid && !endedAt.Valid
+	return entry, nil
+}
+
+// runningTimer возвращает запущенный таймер пользователя, либо sql.ErrNoRows
+func runningTimer(q querier, userID int) (models.TimeEntry, error) {
+	return scanTimeEntry(q.QueryRow(timeEntrySelectSQL+`
+        WHERE te.user_id = ? AND te.started_at IS NOT NULL AND te.ended_at IS NULL
+    `, userID))
+}
+
+// Ответ на попытку изменить время в закрытой неделе
+var weekLockedError = gin.H{"error": "Табель за эту неделю отправлен или утверждён, время в нём менять нельзя"}
+
+// requireOpenWeek отвечает 409, если табель пользователя за неделю с датой date отправлен или утверждён
+func (h *TimeEntryHandler) requireOpenWeek(c *gin.Context, userID int, date string) bool {
+	locked, err := weekLocked(h.db, userID, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if locked {
+		c.JSON(http.StatusConflict, weekLockedError)
+		return false
+	}
+	return true
+}
+
+func (h *TimeEntryHandler) GetTaskTime(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(timeEntrySelectSQL+`
+        WHERE te.task_id = ?
+        ORDER BY te.entry_date DESC, te.id DESC
+    `, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// AddTimeEntry добавляет ручную запись: сколько минут текущий пользователь потратил на задачу в указанный день
+func (h *TimeEntryHandler) AddTimeEntry(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Date    string `json:"date"` // по умолчанию — сегодня
+		Minutes int    `json:"minutes"`
+		Note    string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	today := localDate(time.Now())
+	date := today
+	if request.Date != "" {
+		parsed, err := time.Parse(dateLayout, request.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Дата должна быть в формате ГГГГ-ММ-ДД"})
+			return
+		}
+		date = parsed
+	}
+	if date.After(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя учесть время за будущую дату"})
+		return
+	}
+	if request.Minutes < 1 || request.Minutes > maxEntryMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Количество минут должно быть от 1 до " + strconv.Itoa(maxEntryMinutes)})
+		return
+	}
+	if !h.requireOpenWeek(c, c.GetInt("userID"), date.Format(dateLayout)) {
+		return
+	}
+
+	result, err := h.db.Exec(
+		"INSERT INTO time_entries (task_id, user_id, entry_date, minutes, note) VALUES (?, ?, ?, ?, ?)",
+		taskID, c.GetInt("userID"), date.Format(dateLayout), request.Minutes, strings.TrimSpace(request.Note),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	entry, err := scanTimeEntry(h.db.QueryRow(timeEntrySelectSQL+" WHERE te.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// DeleteTimeEntry удаляет запись; удалить можно только свою запись, админ — любую.
+// Удаление запущенного таймера отменяет его; минут у него ещё нет, поэтому это можно и в закрытой неделе.
+func (h *TimeEntryHandler) DeleteTimeEntry(c *gin.Context) {
+	taskID, ok := requireVisibleTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	entryID, err := strconv.Atoi(c.Param("entryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time entry ID"})
+		return
+	}
+
+	var authorID int
+	var date time.Time
+	var running bool
+	err = h.db.QueryRow(`
+        SELECT user_id, entry_date, started_at IS NOT NULL AND ended_at IS NULL FROM time_entries WHERE id = ? AND task_id = ?
+    `, entryID, taskID).Scan(&authorID, &date, &running)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Запись не найдена"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if authorID != c.GetInt("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Удалять запись может только её автор или администратор"})
+		return
+	}
+	if !running && !h.requireOpenWeek(c, authorID, date.Format(dateLayout)) {
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM time_entries WHERE id = ?", entryID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Запись удалена"})
+}
+
+// StartTimer запускает таймер по задаче; у пользователя может идти только один таймер
+func (h *TimeEntryHandler) StartTimer(c *gin.Context) {
+	taskID, ok := requireEditableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Note string `json:"note"`
+	}
+	// Тело необязательно
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := c.GetInt("userID")
+	// Дата записи — местная, как у ручных записей и отчётов, а не UTC-дата SQLite
+	today := localDate(time.Now()).Format(dateLayout)
+	if !h.requireOpenWeek(c, userID, today) {
+		return
+	}
+
+	// Проверки и вставка одним запросом: второй таймер не появится, а неделя не закроется,
+	// даже при параллельных запросах
+	result, err := h.db.Exec(`
+        INSERT INTO time_entries (task_id, user_id, entry_date, started_at, note)
+        SELECT ?, ?, ?, CURRENT_TIMESTAMP, ?
+        WHERE NOT EXISTS (
+            SELECT 1 FROM time_entries WHERE user_id = ? AND started_at IS NOT NULL AND ended_at IS NULL
+        ) AND NOT `+weekLockedSQL, taskID, userID, today, strings.TrimSpace(request.Note), userID, userID, today, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		running, err := runningTimer(h.db, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusConflict, weekLockedError)
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "У вас уже запущен таймер", "timer": running})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	entry, err := scanTimeEntry(h.db.QueryRow(timeEntrySelectSQL+" WHERE te.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// StopTimer останавливает запущенный таймер текущего пользователя и записывает затраченные минуты.
+// Как и ручная запись, одна запись таймера не превышает maxEntryMinutes.
+// Неделя с идущим таймером не отправляется и не утверждается, поэтому закрытой она здесь
+// может оказаться только в данных, созданных до этой проверки; такой таймер не останавливается,
+// его можно только удалить.
+func (h *TimeEntryHandler) StopTimer(c *gin.Context) {
+	userID := c.GetInt("userID")
+	running, err := runningTimer(h.db, userID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Таймер не запущен"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.requireOpenWeek(c, userID, running.Date) {
+		return
+	}
+
+	_, err = h.db.Exec(`
+        UPDATE time_entries
+        SET ended_at = CURRENT_TIMESTAMP,
+            minutes = MIN(CAST(ROUND((JULIANDAY(CURRENT_TIMESTAMP) - JULIANDAY(started_at)) * 1440) AS INTEGER), ?)
+        WHERE id = ? AND ended_at IS NULL
+    `, maxEntryMinutes, running.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := scanTimeEntry(h.db.QueryRow(timeEntrySelectSQL+" WHERE te.id = ?", running.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetTimer возвращает запущенный таймер текущего пользователя или null
+func (h *TimeEntryHandler) GetTimer(c *gin.Context) {
+	running, err := runningTimer(h.db, c.GetInt("userID"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusOK, gin.H{"timer": nil})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timer": running})
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var isoWeekPattern = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+
+// Часы табеля t — записи учёта времени его сотрудника за неделю
+const timesheetHoursSQL = `ROUND(COALESCE((
+    SELECT SUM(te.minutes) FROM time_entries te
+    WHERE te.user_id = t.user_id AND te.entry_date BETWEEN t.week_start AND DATE(t.week_start, '+6 days')
+), 0) / 60.0, 2)`
+
+const timesheetSelectSQL = `
+    SELECT t.id, t.user_id, u.username, COALESCE(u.department, ''), t.week, t.week_start, t.status,
+           ` + timesheetHoursSQL + `, t.comment, t.submitted_at, t.reviewed_by, COALESCE(r.username, ''),
+           t.reviewed_at, t.created_at
+    FROM timesheets t
+    JOIN users u ON t.user_id = u.id
+    LEFT JOIN users r ON t.reviewed_by = r.id`
+
+type TimesheetHandler struct {
+	db *sql.DB
+}
+
+func NewTimesheetHandler(db *sql.DB) *TimesheetHandler {
+	return &TimesheetHandler{db: db}
+}
+
+// isoWeek возвращает ISO-неделю даты в виде 2026-W42
+func isoWeek(date time.Time) string {
+	year, week := date.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// parseISOWeek разбирает неделю вида 2026-W42 и возвращает её понедельник
+func parseISOWeek(week string) (time.Time, bool) {
+	match := isoWeekPattern.FindStringSubmatch(week)
+	if match == nil {
+		return time.Time{}, false
+	}
+	year, _ := strconv.Atoi(match[1])
+	num, _ := strconv.Atoi(match[2])
+
+	// 4 января всегда попадает в первую ISO-неделю года
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(num-1)*7)
+
+	if y, w := monday.ISOWeek(); y != year || w != num {
+		return time.Time{}, false
+	}
+	return monday, true
+}
+
+func scanTimesheet(row rowScanner) (models.Timesheet, error) {
+	var ts models.Timesheet
+	var weekStart time.Time
+	var submittedAt, reviewedAt sql.NullTime
+	var reviewedBy sql.NullInt64
+	err := row.Scan(&ts.ID, &ts.UserID, &ts.Username, &ts.Department, &ts.Week, &weekStart, &ts.Status,
+		&ts.Hours, &ts.Comment, &submittedAt, &reviewedBy, &ts.ReviewerName, &reviewedAt, &ts.CreatedAt)
+	if err != nil {
+		return ts, err
+	}
+
+	ts.WeekStart = weekStart.Format(dateLayout)
+	ts.WeekEnd = weekStart.AddDate(0, 0, 6).Format(dateLayout)
+	if submittedAt.Valid {
+		ts.SubmittedAt = &submittedAt.Time
+	}
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		ts.ReviewedBy = &id
+	}
+	if reviewedAt.Valid {
+		ts.ReviewedAt = &reviewedAt.Time
+	}
+	return ts, nil
+}
+
+// Табели в этих статусах закрывают неделю: часы считаются по записям учёта времени,
+// поэтому после отправки они не должны меняться. Отклонённый табель неделю снова открывает.
+const lockedTimesheetSQL = `status IN ('submitted', 'approved')`
+
+// Табель пользователя (первый параметр) за неделю с датой (второй и третий параметры) закрывает неделю
+const weekLockedSQL = `EXISTS (
+        SELECT 1 FROM timesheets
+        WHERE user_id = ? AND ` + lockedTimesheetSQL + ` AND week_start <= ? AND DATE(week_start, '+6 days') >= ?
+    )`
+
+// В неделе табеля t идёт таймер его сотрудника
+const runningTimerInWeekSQL = `EXISTS (
+        SELECT 1 FROM time_entries te
+        WHERE te.user_id = t.user_id AND te.started_at IS NOT NULL AND te.ended_at IS NULL
+          AND te.entry_date BETWEEN t.week_start AND DATE(t.week_start, '+6 days')
+    )`
+
+// weekLocked — отправлен или утверждён ли табель пользователя за неделю, в которую попадает date (ГГГГ-ММ-ДД).
+// Время в такой неделе менять нельзя.
+func weekLocked(q querier, userID int, date string) (bool, error) {
+	var locked bool
+	err := q.QueryRow("SELECT "+weekLockedSQL, userID, date, date).Scan(&locked)
+	return locked, err
+}
+
+// У задачи t есть время в отправленных или утверждённых табелях. Такую задачу нельзя удалить окончательно:
+// часы табеля и выгрузка считаются по записям учёта времени.
+const lockedTimeSQL = `EXISTS (
+        SELECT 1 FROM time_entries te
+        JOIN timesheets ts ON ts.user_id = te.user_id AND ts.` + lockedTimesheetSQL + `
+             AND te.entry_date BETWEEN ts.week_start AND DATE(ts.week_start, '+6 days')
+        WHERE te.task_id = t.id
+    )`
+
+// GetTimesheets возвращает табели: админ — все, менеджер — своего отдела, пользователь — свои.
+// Фильтры: ?status=, ?week=, ?user_id=
+func (h *TimesheetHandler) GetTimesheets(c *gin.Context) {
+	scope, args := roleScope(c)
+	query := timesheetSelectSQL + " WHERE " + scope
+
+	if status := c.Query("status"); status != "" {
+		query += " AND t.status = ?"
+		args = append(args, status)
+	}
+	if week := c.Query("week"); week != "" {
+		query += " AND t.week = ?"
+		args = append(args, week)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		query += " AND t.user_id = ?"
+		args = append(args, id)
+	}
+	query += " ORDER BY t.week_start DESC, u.username"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	timesheets := []models.Timesheet{}
+	for rows.Next() {
+		ts, err := scanTimesheet(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		timesheets = append(timesheets, ts)
+	}
+
+	c.JSON(http.StatusOK, timesheets)
+}
+
+// CreateTimesheet заводит черновик табеля текущего пользователя на неделю (по умолчанию — текущую).
+// Если табель на эту неделю уже есть, возвращает его.
+func (h *TimesheetHandler) CreateTimesheet(c *gin.Context) {
+	var request struct {
+		Week string `json:"week"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	today := localDate(time.Now())
+	if request.Week == "" {
+		request.Week = isoWeek(today)
+	}
+	weekStart, ok := parseISOWeek(request.Week)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неделя должна быть в формате ГГГГ-Wнн, например 2026-W42"})
+		return
+	}
+	if weekStart.After(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя завести табель на будущую неделю"})
+		return
+	}
+
+	userID := c.GetInt("userID")
+	result, err := h.db.Exec("INSERT OR IGNORE INTO timesheets (user_id, week, week_start) VALUES (?, ?, ?)",
+		userID, request.Week, weekStart.Format(dateLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ts, err := scanTimesheet(h.db.QueryRow(timesheetSelectSQL+" WHERE t.user_id = ? AND t.week = ?", userID, request.Week))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusCreated
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		status = http.StatusOK
+	}
+	c.JSON(status, ts)
+}
+
+// loadTimesheet находит табель по :id в пределах видимости пользователя
+func (h *TimesheetHandler) loadTimesheet(c *gin.Context) (models.Timesheet, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timesheet ID"})
+		return models.Timesheet{}, false
+	}
+
+	scope, args := roleScope(c)
+	ts, err := scanTimesheet(h.db.QueryRow(timesheetSelectSQL+" WHERE t.id = ? AND "+scope, append([]interface{}{id}, args...)...))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Табель не найден"})
+		return ts, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return ts, false
+	}
+	return ts, true
+}
+
+// GetTimesheet возвращает табель вместе с записями учёта времени за неделю
+func (h *TimesheetHandler) GetTimesheet(c *gin.Context) {
+	ts, ok := h.loadTimesheet(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(timeEntrySelectSQL+`
+        WHERE te.user_id = ? AND te.entry_date BETWEEN ? AND ?
+        ORDER BY te.entry_date, te.id
+    `, ts.UserID, ts.WeekStart, ts.WeekEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	ts.Entries = []models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ts.Entries = append(ts.Entries, entry)
+	}
+
+	c.JSON(http.StatusOK, ts)
+}
+
+// SubmitTimesheet отправляет черновик или отклонённый табель на утверждение; отправляет только сам сотрудник.
+// С отправкой неделя закрывается, поэтому табель с идущим в этой неделе таймером не отправляется.
+func (h *TimesheetHandler) SubmitTimesheet(c *gin.Context) {
+	ts, ok := h.loadTimesheet(c)
+	if !ok {
+		return
+	}
+	if ts.UserID != c.GetInt("userID") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Отправить табель может только его владелец"})
+		return
+	}
+	if ts.Status != "draft" && ts.Status != "rejected" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Табель уже отправлен на утверждение"})
+		return
+	}
+
+	// Прошлое решение и комментарий проверяющего относятся к прошлой версии табеля.
+	// Таймер проверяется в том же запросе, чтобы запущенный между проверкой и отправкой не проскочил.
+	result, err := h.db.Exec(`
+        UPDATE timesheets AS t
+        SET status = 'submitted', submitted_at = CURRENT_TIMESTAMP, comment = '', reviewed_by = NULL, reviewed_at = NULL
+        WHERE t.id = ? AND t.status IN ('draft', 'rejected') AND NOT `+runningTimerInWeekSQL, ts.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		h.respondReviewConflict(c, ts.ID, "Табель уже отправлен на утверждение", "draft", "rejected")
+		return
+	}
+
+	h.respondTimesheet(c, ts.ID)
+}
+
+func (h *TimesheetHandler) ApproveTimesheet(c *gin.Context) {
+	h.reviewTimesheet(c, "approved")
+}
+
+func (h *TimesheetHandler) RejectTimesheet(c *gin.Context) {
+	h.reviewTimesheet(c, "rejected")
+}
+
+// reviewTimesheet утверждает или отклоняет отправленный табель. Менеджер проверяет табели своего отдела,
+// но не свои; при отклонении комментарий обязателен.
+func (h *TimesheetHandler) reviewTimesheet(c *gin.Context, status string) {
+	ts, ok := h.loadTimesheet(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Comment string `json:"comment"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	request.Comment = strings.TrimSpace(request.Comment)
+
+	userID := c.GetInt("userID")
+	if ts.UserID == userID && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Нельзя проверять собственный табель"})
+		return
+	}
+	if ts.Status != "submitted" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Проверить можно только отправленный табель"})
+		return
+	}
+	if status == "rejected" && request.Comment == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите причину отклонения"})
+		return
+	}
+
+	// Минуты идущего таймера записались бы при остановке, уже после утверждения.
+	// Таймер проверяется в том же запросе, что и меняет статус.
+	query := `
+        UPDATE timesheets AS t SET status = ?, comment = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP
+        WHERE t.id = ? AND t.status = 'submitted'`
+	if status == "approved" {
+		query += " AND NOT " + runningTimerInWeekSQL
+	}
+	result, err := h.db.Exec(query, status, request.Comment, userID, ts.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		h.respondReviewConflict(c, ts.ID, "Проверить можно только отправленный табель", "submitted")
+		return
+	}
+
+	h.respondTimesheet(c, ts.ID)
+}
+
+// respondReviewConflict отвечает 409, когда смена статуса табеля не применилась: с сообщением statusMsg,
+// если табель уже не в одном из статусов allowed, иначе — из-за идущего в неделе таймера
+func (h *TimesheetHandler) respondReviewConflict(c *gin.Context, id int, statusMsg string, allowed ...string) {
+	var status string
+	if err := h.db.QueryRow("SELECT status FROM timesheets WHERE id = ?", id).Scan(&status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, s := range allowed {
+		if s == status {
+			c.JSON(http.StatusConflict, gin.H{"error": "У сотрудника запущен таймер в этой неделе"})
+			return
+		}
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": statusMsg})
+}
+
+func (h *TimesheetHandler) respondTimesheet(c *gin.Context, id int) {
+	ts, err := scanTimesheet(h.db.QueryRow(timesheetSelectSQL+" WHERE t.id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ts)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"task-management-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Формат deleted_at; совпадает с CURRENT_TIMESTAMP, чтобы даты корректно сравнивались строками
+const deletedAtLayout = "2006-01-02 15:04:05"
+
+// GetTrash возвращает задачи из корзины с теми же ограничениями по ролям, что и GetTasks
+func (h *TaskHandler) GetTrash(c *gin.Context) {
+	scope, args := roleScope(c)
+
+	rows, err := h.db.Query(`
+        SELECT `+taskColumnsSQL+`, t.deleted_at
+        FROM tasks t `+taskJoinsSQL+`
+        WHERE t.deleted_at IS NOT NULL AND `+scope+`
+        ORDER BY t.deleted_at DESC, t.id
+    `, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var deletedAt time.Time
+		task, err := scanTask(rows, &deletedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		task.DeletedAt = &deletedAt
+		tasks = append(tasks, task)
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// RestoreTask возвращает задачу из корзины вместе с подзадачами, удалёнными одновременно с ней.
+// Если родитель задачи всё ещё в корзине или уже удалён окончательно, задача становится задачей верхнего уровня.
+func (h *TaskHandler) RestoreTask(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+	userID := c.GetInt("userID")
+
+	scope, args := roleScope(c)
+	var deletedAt string
+	var parentID sql.NullInt64
+	err = h.db.QueryRow(`
+        SELECT CAST(t.deleted_at AS TEXT), t.parent_id FROM tasks t JOIN users u ON t.user_id = u.id
+        WHERE t.id = ? AND t.deleted_at IS NOT NULL AND `+scope, append([]interface{}{taskID}, args...)...).Scan(&deletedAt, &parentID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Задача не найдена в корзине"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if parentID.Valid {
+		var exists int
+		err := tx.QueryRow("SELECT 1 FROM tasks WHERE id = ? AND deleted_at IS NULL", parentID.Int64).Scan(&exists)
+		if err == sql.ErrNoRows {
+			parentID.Valid = false
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	rows, err := tx.Query(`
+        WITH RECURSIVE tree(id) AS (
+            SELECT ?
+            UNION
+            SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at = ?
+        )
+        SELECT id FROM tree
+    `, taskID, deletedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var restored []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		restored = append(restored, id)
+	}
+	rows.Close()
+
+	for _, id := range restored {
+		query := "UPDATE tasks SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+		if id == taskID && !parentID.Valid {
+			query = "UPDATE tasks SET deleted_at = NULL, parent_id = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+		}
+		if _, err := tx.Exec(query, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		after, err := taskSnapshot(tx, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := recordHistory(tx, id, userID, "restore", nil, after); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Прогресс пересчитывается от восстановленной задачи вверх по родителям
+	if err := rollupProgress(tx, taskID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Задача восстановлена", "restored": restored})
+}
+
+// PurgeTrash окончательно удаляет задачи, пролежавшие в корзине не меньше older_than_days дней
+// (по умолчанию — все задачи из корзины). Задачи со временем в отправленных или утверждённых табелях остаются в корзине (kept).
+func (h *TaskHandler) PurgeTrash(c *gin.Context) {
+	days := 0
+	if value := c.Query("older_than_days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than_days должен быть неотрицательным числом"})
+			return
+		}
+		days = n
+	}
+
+	purged, kept, err := PurgeDeletedTasks(h.db, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Корзина очищена", "purged": purged, "kept": kept})
+}
+
+// purgeTask окончательно удаляет задачу вместе с зависимостями, комментариями, метками, учётом времени, наблюдателями, чек-листом и вложениями.
+// Возвращает sha256 вложений, файлы которых могли остаться без ссылок.
+func purgeTask(q querier, taskID int) ([]string, error) {
+	before, err := taskSnapshot(q, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := q.Exec("DELETE FROM task_dependencies WHERE task_id = ? OR blocked_by_id = ?", taskID, taskID); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM task_comments WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM task_labels WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM time_entries WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM task_watchers WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM task_checklist_items WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	hashes, err := attachmentHashes(q, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM task_attachments WHERE task_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	// Подзадачи, оставшиеся после удаления родителя, становятся задачами верхнего уровня
+	if _, err := q.Exec("UPDATE tasks SET parent_id = NULL, version = version + 1 WHERE parent_id = ?", taskID); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM tasks WHERE id = ?", taskID); err != nil {
+		return nil, err
+	}
+
+	return hashes, recordHistory(q, taskID, 0, "purge", before, nil)
+}
+
+// PurgeDeletedTasks окончательно удаляет задачи, которые лежат в корзине не меньше olderThanDays дней.
+// Задачи со временем в отправленных или утверждённых табелях не удаляются, их ID возвращаются вторым значением.
+func PurgeDeletedTasks(db *sql.DB, olderThanDays int) ([]int, []int, error) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -olderThanDays).Format(deletedAtLayout)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        SELECT t.id, `+lockedTimeSQL+` FROM tasks t
+        WHERE t.deleted_at IS NOT NULL AND t.deleted_at <= ?
+        ORDER BY t.id
+    `, cutoff)
+	if err != nil {
+		return nil, nil, err
+	}
+	purged, kept := []int{}, []int{}
+	for rows.Next() {
+		var id int
+		var locked bool
+		if err := rows.Scan(&id, &locked); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		if locked {
+			kept = append(kept, id)
+		} else {
+			purged = append(purged, id)
+		}
+	}
+	rows.Close()
+
+	var orphanFiles []string
+	for _, id := range purged {
+		hashes, err := purgeTask(tx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		orphanFiles = append(orphanFiles, hashes...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	removeUnreferencedFiles(db, orphanFiles)
+
+	return purged, kept, nil
+}
+
+// StartTrashPurge раз в сутки очищает корзину от задач старше retentionDays дней.
+// При retentionDays <= 0 автоочистка отключена.
+func StartTrashPurge(db *sql.DB, retentionDays int) {
+	if retentionDays <= 0 {
+		log.Println("Trash auto-purge disabled")
+		return
+	}
+
+	go func() {
+		for {
+			purged, _, err := PurgeDeletedTasks(db, retentionDays)
+			if err != nil {
+				log.Printf("Trash purge failed: %v", err)
+			} else if len(purged) > 0 {
+				log.Printf("Purged %d tasks from trash", len(purged))
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
@@ -103,13 +103,78 @@ func (h *UserHandler) UpdateUserDepartment(c *gin.Context) {
 		return
 	}
 
-	_, err = h.db.Exec("UPDATE users SET department = ? WHERE id = ?", request.Department, userID)
+	tx, err := h.db.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer tx.Rollback()
 
-	c.JSON(http.StatusOK, gin.H{"message": "Информация об отделе обновлена"})
+	_, err = tx.Exec("UPDATE users SET department = ? WHERE id = ?", request.Department, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	remapped, err := remapTaskStatuses(tx, userID, c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Информация об отделе обновлена", "remapped_tasks": remapped})
+}
+
+// remapTaskStatuses возвращает в начальное состояние задачи сотрудника (включая лежащие в корзине),
+// чьих статусов нет в workflow его отдела, — как при передаче задачи в другой отдел.
+// Возвращает ID таких задач.
+func remapTaskStatuses(q querier, ownerID, userID int) ([]int, error) {
+	wf, err := taskWorkflow(q, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT id, status FROM tasks WHERE user_id = ? ORDER BY id", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	remapped := []int{}
+	for rows.Next() {
+		var id int
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !workflowHasState(wf, status) {
+			remapped = append(remapped, id)
+		}
+	}
+	rows.Close()
+
+	initial := workflowInitialState(wf)
+	for _, id := range remapped {
+		before, err := taskSnapshot(q, id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := q.Exec("UPDATE tasks SET status = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", initial, id); err != nil {
+			return nil, err
+		}
+		after, err := taskSnapshot(q, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordHistory(q, id, userID, "update", before, after); err != nil {
+			return nil, err
+		}
+	}
+	return remapped, nil
 }
 
 func (h *UserHandler) DeleteUser(c *gin.Context) {
@@ -137,7 +202,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// Проверяем, есть ли у пользователя задачи
+	// Проверяем, есть ли у пользователя задачи (включая лежащие в корзине)
 	var taskCount int
 	err = h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE user_id = ?", userID).Scan(&taskCount)
 	if err != nil {
@@ -147,12 +212,72 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	if taskCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "Нельзя удалить пользователя с существующими задачами. Пожалуйста, сначала удалите его задачи.",
+			"error":      "Нельзя удалить пользователя с существующими задачами. Пожалуйста, сначала удалите его задачи и очистите корзину.",
 			"task_count": taskCount,
 		})
 		return
 	}
 
+	// Шаблоны повторяющихся задач продолжили бы создавать задачи на удалённого пользователя
+	var recurringCount int
+	err = h.db.QueryRow("SELECT COUNT(*) FROM recurring_tasks WHERE user_id = ?", userID).Scan(&recurringCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if recurringCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "Нельзя удалить пользователя с повторяющимися задачами. Пожалуйста, сначала удалите или передайте их.",
+			"recurring_count": recurringCount,
+		})
+		return
+	}
+
+	// Учтённое время и табели нужны отчётам, поэтому их автора не удаляем
+	var timeEntryCount int
+	err = h.db.QueryRow(`
+        SELECT (SELECT COUNT(*) FROM time_entries WHERE user_id = ?) + (SELECT COUNT(*) FROM timesheets WHERE user_id = ?)
+    `, userID, userID).Scan(&timeEntryCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if timeEntryCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            "Нельзя удалить пользователя с учтённым временем или табелями.",
+			"time_entry_count": timeEntryCount,
+		})
+		return
+	}
+
+	// Проект без владельца некому вести — сначала нужно передать его другому
+	var projectCount int
+	err = h.db.QueryRow("SELECT COUNT(*) FROM projects WHERE owner_id = ?", userID).Scan(&projectCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if projectCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         "Нельзя удалить владельца проектов. Сначала передайте проекты другому пользователю.",
+			"project_count": projectCount,
+		})
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM project_members WHERE user_id = ?", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Подписки пользователя и выданные им подписки теряют смысл
+	if _, err := h.db.Exec("DELETE FROM task_watchers WHERE user_id = ? OR granted_by = ?", userID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Удаляем пользователя
 	result, err := h.db.Exec("DELETE FROM users WHERE id = ?", userID)
 	if err != nil {
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Задача t видна наблюдателю (параметр — ID пользователя), если его подписал владелец задачи,
+// менеджер или админ. Подписка на самого себя видимость не расширяет.
+const watchGrantSQL = `EXISTS (
+        SELECT 1 FROM task_watchers w JOIN users g ON g.id = w.granted_by
+        WHERE w.task_id = t.id AND w.user_id = ? AND w.granted_by <> w.user_id
+          AND (w.granted_by = t.user_id OR g.role IN ('manager', 'admin'))
+    )`
+
+const taskWatcherSelectSQL = `
+    SELECT w.task_id, w.user_id, COALESCE(u.username, ''), COALESCE(u.department, ''), w.granted_by,
+           COALESCE(g.username, ''), w.created_at
+    FROM task_watchers w
+    LEFT JOIN users u ON w.user_id = u.id
+    LEFT JOIN users g ON w.granted_by = g.id`
+
+type WatcherHandler struct {
+	db *sql.DB
+}
+
+func NewWatcherHandler(db *sql.DB) *WatcherHandler {
+	return &WatcherHandler{db: db}
+}
+
+// canGrantWatch — подписывать других может владелец задачи, менеджер или админ.
+// Задача должна быть видна по taskScope: доступ по подписке передать дальше нельзя.
+func canGrantWatch(q querier, c *gin.Context, taskID int) (bool, error) {
+	visible, err := taskVisible(q, c, taskID)
+	if err != nil || !visible {
+		return false, err
+	}
+	if c.GetString("userRole") != "user" {
+		return true, nil
+	}
+	var ownerID int
+	if err := q.QueryRow("SELECT user_id FROM tasks WHERE id = ?", taskID).Scan(&ownerID); err != nil {
+		return false, err
+	}
+	return ownerID == c.GetInt("userID"), nil
+}
+
+func (h *WatcherHandler) GetWatchers(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(taskWatcherSelectSQL+`
+        WHERE w.task_id = ?
+        ORDER BY w.created_at, w.user_id
+    `, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	watchers := []models.TaskWatcher{}
+	for rows.Next() {
+		var w models.TaskWatcher
+		if err := rows.Scan(&w.TaskID, &w.UserID, &w.Username, &w.Department, &w.GrantedBy, &w.GrantedByName, &w.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		watchers = append(watchers, w)
+	}
+
+	c.JSON(http.StatusOK, watchers)
+}
+
+// Watch подписывает на задачу текущего пользователя, либо ({"user_id": 5}) другого сотрудника.
+// Подписка, выданная владельцем задачи или менеджером, открывает наблюдателю доступ к задаче на чтение.
+func (h *WatcherHandler) Watch(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		UserID int `json:"user_id"`
+	}
+	// Тело необязательно
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := c.GetInt("userID")
+	if request.UserID == 0 || request.UserID == userID {
+		// Своя подписка не заменяет выданную кем-то другим
+		if _, err := h.db.Exec("INSERT OR IGNORE INTO task_watchers (task_id, user_id, granted_by) VALUES (?, ?, ?)", taskID, userID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Вы подписаны на задачу", "task_id": taskID, "user_id": userID})
+		return
+	}
+
+	canGrant, err := canGrantWatch(h.db, c, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !canGrant {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Подписывать других может только владелец задачи или менеджер"})
+		return
+	}
+
+	var exists int
+	err = h.db.QueryRow("SELECT 1 FROM users WHERE id = ?", request.UserID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = h.db.Exec(`
+        INSERT INTO task_watchers (task_id, user_id, granted_by) VALUES (?, ?, ?)
+        ON CONFLICT (task_id, user_id) DO UPDATE SET granted_by = excluded.granted_by
+    `, taskID, request.UserID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Наблюдатель добавлен", "task_id": taskID, "user_id": request.UserID})
+}
+
+// Unwatch отписывает от задачи текущего пользователя, либо (?user_id=5) другого наблюдателя.
+// Чужую подписку снимает тот, кто её выдал, владелец задачи, менеджер или админ.
+func (h *WatcherHandler) Unwatch(c *gin.Context) {
+	taskID, ok := requireReadableTask(h.db, c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetInt("userID")
+	watcherID := userID
+	if value := c.Query("user_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		watcherID = parsed
+	}
+
+	var grantedBy int
+	err := h.db.QueryRow("SELECT granted_by FROM task_watchers WHERE task_id = ? AND user_id = ?", taskID, watcherID).Scan(&grantedBy)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Наблюдатель не найден"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if watcherID != userID && grantedBy != userID {
+		canGrant, err := canGrantWatch(h.db, c, taskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !canGrant {
+			c.JSON(http.StatusForbidden, gin.H{"error": "В доступе отказано"})
+			return
+		}
+	}
+
+	if _, err := h.db.Exec("DELETE FROM task_watchers WHERE task_id = ? AND user_id = ?", taskID, watcherID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Наблюдатель удалён"})
+}
+
+// GetWatched возвращает задачи, на которые подписан текущий пользователь и которые ему видны
+func (h *WatcherHandler) GetWatched(c *gin.Context) {
+	scope, args := taskReadScope(c)
+	rows, err := h.db.Query(taskSelectSQL+`
+        JOIN task_watchers tw ON tw.task_id = t.id AND tw.user_id = ?
+        WHERE `+scope+`
+        ORDER BY tw.created_at DESC, t.id DESC
+    `, append([]interface{}{c.GetInt("userID")}, args...)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
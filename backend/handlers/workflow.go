@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"task-management-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Название статуса задачи с учётом workflow отдела владельца (запрос должен содержать алиасы t и u)
+const statusNameSQL = `COALESCE((
+    SELECT ws.name FROM workflow_states ws
+    WHERE ws.key = t.status AND ws.department IN (COALESCE(u.department, ''), '')
+    ORDER BY ws.department DESC LIMIT 1
+), t.status)`
+
+// Находится ли задача в финальном состоянии своего workflow
+const statusFinalSQL = `COALESCE((
+    SELECT ws.is_final FROM workflow_states ws
+    WHERE ws.key = t.status AND ws.department IN (COALESCE(u.department, ''), '')
+    ORDER BY ws.department DESC LIMIT 1
+), 0)`
+
+// Задача просрочена: срок прошёл, а работа не завершена
+const overdueSQL = `(t.due_date IS NOT NULL AND t.due_date < date('now', 'localtime')
+    AND t.progress < 100 AND NOT ` + statusFinalSQL + `)`
+
+type WorkflowHandler struct {
+	db *sql.DB
+}
+
+func NewWorkflowHandler(db *sql.DB) *WorkflowHandler {
+	return &WorkflowHandler{db: db}
+}
+
+// loadWorkflow возвращает workflow отдела, а если отдел свой не настроил — общий
+func loadWorkflow(q querier, department string) (models.Workflow, error) {
+	var count int
+	if err := q.QueryRow("SELECT COUNT(*) FROM workflow_states WHERE department = ?", department).Scan(&count); err != nil {
+		return models.Workflow{}, err
+	}
+	if count == 0 {
+		department = ""
+	}
+
+	wf := models.Workflow{
+		Department:  department,
+		States:      []models.WorkflowState{},
+		Transitions: []models.WorkflowTransition{},
+	}
+
+	rows, err := q.Query(`
+        SELECT key, name, position, is_initial, is_final
+        FROM workflow_states WHERE department = ?
+        ORDER BY position, id
+    `, department)
+	if err != nil {
+		return wf, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.WorkflowState
+		if err := rows.Scan(&s.Key, &s.Name, &s.Position, &s.IsInitial, &s.IsFinal); err != nil {
+			return wf, err
+		}
+		wf.States = append(wf.States, s)
+	}
+	if err := rows.Err(); err != nil {
+		return wf, err
+	}
+
+	trows, err := q.Query("SELECT from_state, to_state FROM workflow_transitions WHERE department = ? ORDER BY id", department)
+	if err != nil {
+		return wf, err
+	}
+	defer trows.Close()
+
+	for trows.Next() {
+		var t models.WorkflowTransition
+		if err := trows.Scan(&t.From, &t.To); err != nil {
+			return wf, err
+		}
+		wf.Transitions = append(wf.Transitions, t)
+	}
+
+	return wf, trows.Err()
+}
+
+// taskWorkflow возвращает workflow отдела, к которому относится владелец задачи
+func taskWorkflow(q querier, ownerID int) (models.Workflow, error) {
+	var department sql.NullString
+	if err := q.QueryRow("SELECT department FROM users WHERE id = ?", ownerID).Scan(&department); err != nil {
+		return models.Workflow{}, err
+	}
+	return loadWorkflow(q, department.String)
+}
+
+func workflowHasState(wf models.Workflow, key string) bool {
+	for _, s := range wf.States {
+		if s.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func workflowInitialState(wf models.Workflow) string {
+	for _, s := range wf.States {
+		if s.IsInitial {
+			return s.Key
+		}
+	}
+	if len(wf.States) > 0 {
+		return wf.States[0].Key
+	}
+	return ""
+}
+
+func workflowAllows(wf models.Workflow, from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, t := range wf.Transitions {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+func workflowIsFinal(wf models.Workflow, key string) bool {
+	for _, s := range wf.States {
+		if s.Key == key {
+			return s.IsFinal
+		}
+	}
+	return false
+}
+
+func (h *WorkflowHandler) GetWorkflow(c *gin.Context) {
+	department := c.GetString("userDepartment")
+	if c.GetString("userRole") == "admin" {
+		if d, ok := c.GetQuery("department"); ok {
+			department = d
+		}
+	}
+
+	wf, err := loadWorkflow(h.db, department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wf)
+}
+
+func (h *WorkflowHandler) UpdateWorkflow(c *gin.Context) {
+	var request models.Workflow
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Менеджер настраивает только свой отдел, админ — любой или общий ("")
+	department := request.Department
+	if c.GetString("userRole") != "admin" {
+		department = c.GetString("userDepartment")
+	}
+
+	if len(request.States) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workflow должен содержать хотя бы одно состояние"})
+		return
+	}
+
+	keys := map[string]bool{}
+	initialCount := 0
+	for _, s := range request.States {
+		if s.Key == "" || s.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "У состояния должны быть ключ и название"})
+			return
+		}
+		if keys[s.Key] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Повторяющийся ключ состояния: " + s.Key})
+			return
+		}
+		keys[s.Key] = true
+		if s.IsInitial {
+			initialCount++
+		}
+	}
+	if initialCount != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Должно быть ровно одно начальное состояние"})
+		return
+	}
+	for _, t := range request.Transitions {
+		if !keys[t.From] || !keys[t.To] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Переход ссылается на неизвестное состояние: " + t.From + " → " + t.To})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Нельзя убрать состояние, в котором ещё находятся задачи, живущие по этому workflow.
+	// Общий workflow действует для отделов без собственного.
+	var rows *sql.Rows
+	if department != "" {
+		rows, err = tx.Query(`
+            SELECT DISTINCT t.status FROM tasks t
+            JOIN users u ON t.user_id = u.id
+            WHERE u.department = ?
+        `, department)
+	} else {
+		rows, err = tx.Query(`
+            SELECT DISTINCT t.status FROM tasks t
+            JOIN users u ON t.user_id = u.id
+            WHERE COALESCE(u.department, '') NOT IN (SELECT department FROM workflow_states WHERE department != '')
+        `)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var missing []string
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !keys[status] {
+			missing = append(missing, status)
+		}
+	}
+	rows.Close()
+
+	if len(missing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Есть задачи в состояниях, которых нет в новом workflow",
+			"statuses": missing,
+		})
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workflow_states WHERE department = ?", department); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM workflow_transitions WHERE department = ?", department); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i, s := range request.States {
+		position := s.Position
+		if position == 0 {
+			position = i + 1
+		}
+		_, err := tx.Exec(`
+            INSERT INTO workflow_states (department, key, name, position, is_initial, is_final)
+            VALUES (?, ?, ?, ?, ?, ?)
+        `, department, s.Key, s.Name, position, s.IsInitial, s.IsFinal)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for _, t := range request.Transitions {
+		_, err := tx.Exec(`
+            INSERT OR IGNORE INTO workflow_transitions (department, from_state, to_state)
+            VALUES (?, ?, ?)
+        `, department, t.From, t.To)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	wf, err := loadWorkflow(h.db, department)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wf)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"log"
+	"os"
+	"strconv"
 	"task-management-backend/database"
 	"task-management-backend/handlers"
 	"task-management-backend/middleware"
@@ -17,20 +19,54 @@ func main() {
 	}
 	defer db.Close()
 
+	// Автоочистка корзины: задачи старше TRASH_RETENTION_DAYS дней (по умолчанию 30, 0 — не очищать)
+	trashRetentionDays := 30
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid TRASH_RETENTION_DAYS: %s", value)
+		}
+		trashRetentionDays = days
+	}
+	handlers.StartTrashPurge(db, trashRetentionDays)
+
+	// Строгий контроль загрузки: перегружающие сотрудника изменения задач отклоняются
+	if value := os.Getenv("CAPACITY_STRICT"); value != "" {
+		strict, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid CAPACITY_STRICT: %s", value)
+		}
+		handlers.CapacityStrict = strict
+	}
+	handlers.StartRecurringScheduler(db)
+
 	// Создание обработчиков
 	authHandler := handlers.NewAuthHandler(db)
 	taskHandler := handlers.NewTaskHandler(db)
 	userHandler := handlers.NewUserHandler(db)
 	reportHandler := handlers.NewReportHandler(db)
+	workflowHandler := handlers.NewWorkflowHandler(db)
+	commentHandler := handlers.NewCommentHandler(db)
+	attachmentHandler := handlers.NewAttachmentHandler(db)
+	labelHandler := handlers.NewLabelHandler(db)
+	recurringHandler := handlers.NewRecurringHandler(db)
+	timeEntryHandler := handlers.NewTimeEntryHandler(db)
+	timesheetHandler := handlers.NewTimesheetHandler(db)
+	capacityHandler := handlers.NewCapacityHandler(db)
+	projectHandler := handlers.NewProjectHandler(db)
+	watcherHandler := handlers.NewWatcherHandler(db)
+	checklistHandler := handlers.NewChecklistHandler(db)
+	templateHandler := handlers.NewTemplateHandler(db)
+	boardHandler := handlers.NewBoardHandler(db)
 
 	router := gin.Default()
 
 	// Разрешаем все хосты и методы (не забыть потом настроить)
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Content-Disposition")
-		c.Header("Access-Control-Expose-Headers", "Content-Disposition")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Content-Disposition, If-Match")
+		c.Header("Access-Control-Expose-Headers", "Content-Disposition, ETag")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -50,9 +86,117 @@ func main() {
 	{
 		// Задачи
 		api.GET("/tasks", taskHandler.GetTasks)
+		api.POST("/tasks/import", taskHandler.ImportTasks)
+		api.GET("/tasks/search", taskHandler.SearchTasks)
 		api.POST("/tasks", taskHandler.CreateTask)
+		api.POST("/tasks/bulk", taskHandler.BulkTasks)
+		api.GET("/tasks/:id", taskHandler.GetTask)
 		api.PUT("/tasks/:id", taskHandler.UpdateTask)
+		api.PATCH("/tasks/:id", taskHandler.PatchTask)
 		api.DELETE("/tasks/:id", taskHandler.DeleteTask)
+		api.POST("/tasks/:id/restore", taskHandler.RestoreTask)
+		api.GET("/tasks/:id/history", taskHandler.GetTaskHistory)
+		api.GET("/tasks/:id/subtasks", taskHandler.GetSubtasks)
+		api.POST("/tasks/:id/subtasks", taskHandler.CreateSubtask)
+		api.GET("/tasks/:id/dependencies", taskHandler.GetDependencies)
+		api.POST("/tasks/:id/dependencies", taskHandler.AddDependency)
+		api.DELETE("/tasks/:id/dependencies", taskHandler.RemoveDependency)
+		api.PUT("/tasks/:id/assignee", middleware.ManagerOrAdmin(), taskHandler.ReassignTask)
+		api.POST("/tasks/:id/move", boardHandler.MoveTask)
+
+		// Доска
+		api.GET("/board", boardHandler.GetBoard)
+		api.PUT("/board/limits", middleware.ManagerOrAdmin(), boardHandler.UpdateLimits)
+
+		// Корзина
+		api.GET("/trash", taskHandler.GetTrash)
+		api.DELETE("/trash", middleware.AdminOnly(), taskHandler.PurgeTrash)
+
+		// Комментарии к задачам
+		api.GET("/tasks/:id/comments", commentHandler.GetComments)
+		api.POST("/tasks/:id/comments", commentHandler.CreateComment)
+		api.PUT("/tasks/:id/comments/:commentId", commentHandler.UpdateComment)
+		api.DELETE("/tasks/:id/comments/:commentId", commentHandler.DeleteComment)
+
+		// Вложения задач
+		api.GET("/tasks/:id/attachments", attachmentHandler.GetAttachments)
+		api.POST("/tasks/:id/attachments", attachmentHandler.UploadAttachment)
+		api.GET("/tasks/:id/attachments/:attachmentId", attachmentHandler.DownloadAttachment)
+		api.DELETE("/tasks/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment)
+
+		// Наблюдатели задач
+		api.GET("/tasks/:id/watchers", watcherHandler.GetWatchers)
+		api.POST("/tasks/:id/watch", watcherHandler.Watch)
+		api.DELETE("/tasks/:id/watch", watcherHandler.Unwatch)
+		api.GET("/me/watched", watcherHandler.GetWatched)
+
+		// Чек-лист задачи
+		api.GET("/tasks/:id/checklist", checklistHandler.GetChecklist)
+		api.POST("/tasks/:id/checklist", checklistHandler.AddChecklistItem)
+		api.PUT("/tasks/:id/checklist/:itemId", checklistHandler.UpdateChecklistItem)
+		api.DELETE("/tasks/:id/checklist/:itemId", checklistHandler.DeleteChecklistItem)
+
+		// Шаблоны задач и их наборы
+		api.GET("/templates", templateHandler.GetTemplates)
+		api.POST("/templates", middleware.ManagerOrAdmin(), templateHandler.CreateTemplate)
+		api.GET("/templates/:id", templateHandler.GetTemplate)
+		api.PUT("/templates/:id", middleware.ManagerOrAdmin(), templateHandler.UpdateTemplate)
+		api.DELETE("/templates/:id", middleware.ManagerOrAdmin(), templateHandler.DeleteTemplate)
+		api.POST("/templates/:id/instantiate", middleware.ManagerOrAdmin(), templateHandler.InstantiateTemplate)
+		api.GET("/bundles", templateHandler.GetBundles)
+		api.POST("/bundles", middleware.ManagerOrAdmin(), templateHandler.CreateBundle)
+		api.GET("/bundles/:id", templateHandler.GetBundle)
+		api.PUT("/bundles/:id", middleware.ManagerOrAdmin(), templateHandler.UpdateBundle)
+		api.DELETE("/bundles/:id", middleware.ManagerOrAdmin(), templateHandler.DeleteBundle)
+		api.POST("/bundles/:id/instantiate", middleware.ManagerOrAdmin(), templateHandler.InstantiateBundle)
+
+		// Учёт времени
+		api.GET("/tasks/:id/time", timeEntryHandler.GetTaskTime)
+		api.POST("/tasks/:id/time", timeEntryHandler.AddTimeEntry)
+		api.DELETE("/tasks/:id/time/:entryId", timeEntryHandler.DeleteTimeEntry)
+		api.POST("/tasks/:id/timer/start", timeEntryHandler.StartTimer)
+		api.GET("/timer", timeEntryHandler.GetTimer)
+		api.POST("/timer/stop", timeEntryHandler.StopTimer)
+
+		// Недельные табели
+		api.GET("/timesheets", timesheetHandler.GetTimesheets)
+		api.POST("/timesheets", timesheetHandler.CreateTimesheet)
+		api.GET("/timesheets/:id", timesheetHandler.GetTimesheet)
+		api.POST("/timesheets/:id/submit", timesheetHandler.SubmitTimesheet)
+		api.POST("/timesheets/:id/approve", middleware.ManagerOrAdmin(), timesheetHandler.ApproveTimesheet)
+		api.POST("/timesheets/:id/reject", middleware.ManagerOrAdmin(), timesheetHandler.RejectTimesheet)
+
+		// Повторяющиеся задачи
+		api.GET("/recurring", recurringHandler.GetRecurringTasks)
+		api.POST("/recurring", recurringHandler.CreateRecurringTask)
+		api.PUT("/recurring/:id", recurringHandler.UpdateRecurringTask)
+		api.DELETE("/recurring/:id", recurringHandler.DeleteRecurringTask)
+
+		// Метки
+		api.GET("/labels", labelHandler.GetLabels)
+		api.POST("/labels", middleware.ManagerOrAdmin(), labelHandler.CreateLabel)
+		api.PUT("/labels/:id", middleware.ManagerOrAdmin(), labelHandler.UpdateLabel)
+		api.DELETE("/labels/:id", middleware.ManagerOrAdmin(), labelHandler.DeleteLabel)
+		api.POST("/tasks/:id/labels", labelHandler.AddTaskLabel)
+		api.DELETE("/tasks/:id/labels/:labelId", labelHandler.RemoveTaskLabel)
+
+		// Проекты
+		api.GET("/projects", projectHandler.GetProjects)
+		api.POST("/projects", middleware.ManagerOrAdmin(), projectHandler.CreateProject)
+		api.GET("/projects/:id", projectHandler.GetProject)
+		api.PUT("/projects/:id", projectHandler.UpdateProject)
+		api.DELETE("/projects/:id", projectHandler.DeleteProject)
+		api.POST("/projects/:id/members", projectHandler.AddProjectMember)
+		api.DELETE("/projects/:id/members/:userId", projectHandler.RemoveProjectMember)
+		api.GET("/projects/:id/tasks", projectHandler.GetProjectTasks)
+		api.PUT("/tasks/:id/project", projectHandler.SetTaskProject)
+
+		// Загрузка сотрудников
+		api.GET("/capacity", middleware.ManagerOrAdmin(), capacityHandler.GetCapacity)
+
+		// Workflow статусов задач
+		api.GET("/workflow", workflowHandler.GetWorkflow)
+		api.PUT("/workflow", middleware.ManagerOrAdmin(), workflowHandler.UpdateWorkflow)
 
 		// Пользователи (только для админов)
 		api.GET("/users", middleware.AdminOnly(), userHandler.GetUsers)
@@ -64,6 +208,8 @@ func main() {
 		api.GET("/reports/my-tasks", reportHandler.ExportMyTasks)
 		api.GET("/reports/department-tasks", middleware.ManagerOrAdmin(), reportHandler.ExportDepartmentTasks)
 		api.GET("/reports/all-tasks", middleware.AdminOnly(), reportHandler.ExportAllTasks)
+		api.GET("/reports/timesheets", middleware.ManagerOrAdmin(), reportHandler.ExportTimesheets)
+		api.GET("/reports/project-tasks", reportHandler.ExportProjectTasks)
 
 		// Бэкап БД
 		api.GET("/backup", middleware.AdminOnly(), handlers.BackupDB)
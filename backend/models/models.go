@@ -15,17 +15,255 @@ type User struct {
 }
 
 type Task struct {
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Progress     int        `json:"progress"`
+	HoursPerWeek float64    `json:"hours_per_week"` // плановая оценка; фактические часы — logged_hours
+	LoggedHours  float64    `json:"logged_hours"`   // сумма записей учёта времени по задаче
+	LoadPerMonth int        `json:"load_per_month"`
+	Status       string     `json:"status"`
+	StatusName   string     `json:"status_name,omitempty"`
+	Priority     string     `json:"priority"`             // low, normal, high или critical
+	StartDate    string     `json:"start_date,omitempty"` // YYYY-MM-DD
+	DueDate      string     `json:"due_date,omitempty"`   // YYYY-MM-DD
+	IsOverdue    bool       `json:"is_overdue"`
+	ParentID     *int       `json:"parent_id"`
+	RecurringID  *int       `json:"recurring_id,omitempty"` // шаблон, из которого создана задача
+	ProjectID    *int       `json:"project_id"`
+	SubtaskCount int        `json:"subtask_count"`
+	BlockedBy    []int      `json:"blocked_by"`
+	IsBlocked    bool       `json:"is_blocked"`
+	Labels       []Label    `json:"labels"`
+	Version      int        `json:"version"`  // растёт при каждом изменении, отдаётся в ETag
+	Position     float64    `json:"position"` // порядок в колонке доски
+	UserID       int        `json:"user_id"`
+	AssigneeID   int        `json:"assignee_id,omitempty"` // только во входящем запросе: на кого создать задачу
+	CreatedBy    int        `json:"created_by"`
+	CreatorName  string     `json:"created_by_username,omitempty"`
+	Username     string     `json:"username,omitempty"`
+	Department   string     `json:"department,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"` // только для задач в корзине
+
+	CapacityWarnings []UserCapacity `json:"capacity_warnings,omitempty"` // только в ответе: месяцы, где задача перегрузила исполнителя
+}
+
+type TaskComment struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Body      string    `json:"body"`
+	Edited    bool      `json:"edited"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TaskAttachment struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Filename  string    `json:"filename"`
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	MimeType  string    `json:"mime_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TaskWatcher struct {
+	TaskID        int       `json:"task_id"`
+	UserID        int       `json:"user_id"`
+	Username      string    `json:"username"`
+	Department    string    `json:"department"`
+	GrantedBy     int       `json:"granted_by"`
+	GrantedByName string    `json:"granted_by_username"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type RecurringTask struct {
 	ID           int       `json:"id"`
 	Title        string    `json:"title"`
 	Description  string    `json:"description"`
-	Progress     int       `json:"progress"`
 	HoursPerWeek float64   `json:"hours_per_week"`
 	LoadPerMonth int       `json:"load_per_month"`
+	Priority     string    `json:"priority"`
+	Frequency    string    `json:"frequency"`             // daily, weekly или monthly
+	Interval     int       `json:"interval"`              // каждые N дней/недель/месяцев
+	StartDate    string    `json:"start_date"`            // YYYY-MM-DD, первое повторение
+	EndDate      string    `json:"end_date,omitempty"`    // YYYY-MM-DD, после неё задачи не создаются
+	DueInDays    *int      `json:"due_in_days,omitempty"` // срок задачи: через N дней после повторения
+	Active       bool      `json:"active"`
+	LastRunDate  string    `json:"last_run_date,omitempty"`
+	NextRunDate  string    `json:"next_run_date,omitempty"`
 	UserID       int       `json:"user_id"`
+	AssigneeID   int       `json:"assignee_id,omitempty"` // только во входящем запросе
+	CreatedBy    int       `json:"created_by"`
 	Username     string    `json:"username,omitempty"`
 	Department   string    `json:"department,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TaskTemplate — заготовка задачи; due_in_days отсчитывается от даты создания задачи по шаблону
+type TaskTemplate struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	HoursPerWeek float64   `json:"hours_per_week"`
+	LoadPerMonth int       `json:"load_per_month"`
+	Priority     string    `json:"priority"`
+	DueInDays    *int      `json:"due_in_days,omitempty"`
+	Checklist    []string  `json:"checklist"`
+	Department   string    `json:"department"` // "" — общий шаблон
+	CreatedBy    int       `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TemplateBundle — упорядоченный набор шаблонов, создаваемых одним запросом
+type TemplateBundle struct {
+	ID          int            `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Department  string         `json:"department"`
+	TemplateIDs []int          `json:"template_ids"`
+	Templates   []TaskTemplate `json:"templates,omitempty"`
+	CreatedBy   int            `json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type ChecklistItem struct {
+	ID     int        `json:"id"`
+	TaskID int        `json:"task_id"`
+	Text   string     `json:"text"`
+	Done   bool       `json:"done"`
+	DoneBy *int       `json:"done_by,omitempty"`
+	DoneAt *time.Time `json:"done_at,omitempty"`
+}
+
+// TimeEntry — запись учёта времени: таймер (started_at/ended_at) или ручной ввод за дату
+type TimeEntry struct {
+	ID        int        `json:"id"`
+	TaskID    int        `json:"task_id"`
+	TaskTitle string     `json:"task_title,omitempty"`
+	UserID    int        `json:"user_id"`
+	Username  string     `json:"username"`
+	Date      string     `json:"date"` // YYYY-MM-DD, день, к которому относится время
+	Minutes   int        `json:"minutes"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Running   bool       `json:"running"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Timesheet — недельный табель сотрудника: draft, submitted, approved или rejected.
+// Часы считаются по записям учёта времени за неделю.
+type Timesheet struct {
+	ID           int         `json:"id"`
+	UserID       int         `json:"user_id"`
+	Username     string      `json:"username"`
+	Department   string      `json:"department"`
+	Week         string      `json:"week"`       // ISO-неделя, например 2026-W42
+	WeekStart    string      `json:"week_start"` // YYYY-MM-DD, понедельник
+	WeekEnd      string      `json:"week_end"`   // YYYY-MM-DD, воскресенье
+	Status       string      `json:"status"`
+	Hours        float64     `json:"hours"`
+	Comment      string      `json:"comment"` // комментарий проверяющего
+	SubmittedAt  *time.Time  `json:"submitted_at,omitempty"`
+	ReviewedBy   *int        `json:"reviewed_by,omitempty"`
+	ReviewerName string      `json:"reviewed_by_username,omitempty"`
+	ReviewedAt   *time.Time  `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Entries      []TimeEntry `json:"entries,omitempty"`
+}
+
+// UserCapacity — загрузка сотрудника за месяц: сумма load_per_month его незавершённых задач
+type UserCapacity struct {
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username"`
+	Department string `json:"department"`
+	Month      string `json:"month"` // YYYY-MM
+	Load       int    `json:"load"`
+	Capacity   int    `json:"capacity"`
+	TaskCount  int    `json:"task_count"`
+	Overloaded bool   `json:"overloaded"`
+}
+
+type DepartmentCapacity struct {
+	Department      string `json:"department"`
+	Users           int    `json:"users"`
+	Load            int    `json:"load"`
+	Capacity        int    `json:"capacity"` // 100% на каждого сотрудника
+	OverloadedUsers int    `json:"overloaded_users"`
+	Overloaded      bool   `json:"overloaded"`
+}
+
+// BoardColumn — колонка доски: состояние workflow и задачи в нём по порядку
+type BoardColumn struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	IsFinal  bool   `json:"is_final"`
+	WIPLimit *int   `json:"wip_limit"`
+	Count    int    `json:"count"`
+	Tasks    []Task `json:"tasks"`
+}
+
+type Board struct {
+	Department string        `json:"department"`
+	Columns    []BoardColumn `json:"columns"`
+}
+
+// Project — проект: объединяет задачи сотрудников разных отделов
+type Project struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Status      string          `json:"status"`               // planned, active, on_hold, completed или cancelled
+	StartDate   string          `json:"start_date,omitempty"` // YYYY-MM-DD
+	EndDate     string          `json:"end_date,omitempty"`   // YYYY-MM-DD
+	OwnerID     int             `json:"owner_id"`
+	OwnerName   string          `json:"owner_username,omitempty"`
+	CreatedBy   int             `json:"created_by"`
+	Progress    int             `json:"progress"` // по задачам проекта, с теми же весами, что у подзадач
+	TaskCount   int             `json:"task_count"`
+	Members     []ProjectMember `json:"members,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type ProjectMember struct {
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username"`
+	Department string `json:"department"`
+}
+
+type Label struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`      // #RRGGBB или пусто
+	Department string `json:"department"` // "" — общая метка
+}
+
+type WorkflowState struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Position  int    `json:"position"`
+	IsInitial bool   `json:"is_initial"`
+	IsFinal   bool   `json:"is_final"`
+}
+
+type WorkflowTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Workflow struct {
+	Department  string               `json:"department"`
+	States      []WorkflowState      `json:"states"`
+	Transitions []WorkflowTransition `json:"transitions"`
 }
 
 type LoginRequest struct {
@@ -20,7 +20,7 @@ const BackupManager = () => {
             link.href = url;
             
             const timestamp = new Date().toISOString().slice(0, 19).replace(/:/g, '-');
-            link.setAttribute('download', `tasks_backup_${timestamp}.db`);
+            link.setAttribute('download', `tasks_backup_${timestamp}.zip`);
             
             document.body.appendChild(link);
             link.click();
@@ -95,17 +95,17 @@ const BackupManager = () => {
 
                 <div className="backup-card">
                     <h3>Восстановление из бекапа</h3>
-                    <p>Восстановление из бекапа (.db формат только).</p>
+                    <p>Восстановление из бекапа (.zip с вложениями или .db).</p>
                     
                     <form onSubmit={handleRestore} className="restore-form">
                         <div className="form-group">
                             <input
                                 id="restore-file"
                                 type="file"
-                                accept=".db"
+                                accept=".zip,.db"
                                 onChange={(e) => setRestoreFile(e.target.files[0])}
                             />
-                            <small>Только .db файлы допустимы</small>
+                            <small>Только .zip и .db файлы допустимы</small>
                         </div>
                         
                         <button 